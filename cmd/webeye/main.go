@@ -15,12 +15,13 @@ import (
 	"github.com/urfave/cli/v3"
 )
 
-func LoadFinger(templateFileURL string) (*finger.WebFingerSystem, error) {
+// LoadFinger 加载指纹文件，format 为空或 auto 时自动识别格式
+func LoadFinger(templateFileURL string, format string) (*finger.WebFingerSystem, error) {
 	content, err := utils.GetLocalFileOrWeb(templateFileURL)
 	if err != nil {
 		return nil, err
 	}
-	return finger.ParseWebFinger(string(content))
+	return finger.ParseWebFingerFormat(format, string(content))
 }
 
 func LoadTarget(targetListFileURL string) (targets []string, err error) {
@@ -45,7 +46,12 @@ func main() {
 					&cli.StringFlag{
 						Name: "template",
 						Value: "https://raw.githubusercontent.com/0x727/FingerprintHub/refs/heads/main/web_fingerprint_v3.json",
-						Usage: "template for fingerprint (format: FingerprintHub V3, from: filepath or url(with http(s)://))",
+						Usage: "template for fingerprint (format: see --format, from: filepath or url(with http(s)://))",
+					},
+					&cli.StringFlag{
+						Name: "format",
+						Value: finger.FormatAuto,
+						Usage: "format of template (" + strings.Join(append([]string{finger.FormatAuto}, finger.Formats()...), ", ") + ")",
 					},
 					&cli.StringFlag{
 						Name: "target-list",
@@ -57,9 +63,31 @@ func main() {
 						Value: 0,
 						Usage: "how many targets can be scanned simultaneously, default unlimited",
 					},
+					&cli.IntFlag{
+						Name: "probe-threads",
+						Value: 5,
+						Usage: "how many custom probes can be sent to one target simultaneously",
+					},
+					&cli.BoolFlag{
+						Name: "continue-on-error",
+						Usage: "continue with the remaining custom probes when one of them fails",
+					},
+					&cli.FloatFlag{
+						Name: "min-confidence",
+						Value: 0,
+						Usage: "only output fingerprints with confidence (0~1) not lower than this",
+					},
+					&cli.StringSliceFlag{
+						Name: "tag",
+						Usage: "only output fingerprints with any of the tags (case insensitive)",
+					},
+					&cli.StringSliceFlag{
+						Name: "category",
+						Usage: "only output fingerprints in any of the categories, e.g. CMS, WAF, OA (case insensitive)",
+					},
 				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
-					wfs, err := LoadFinger(cmd.String("template"))
+					wfs, err := LoadFinger(cmd.String("template"), cmd.String("format"))
 					if err != nil {
 						return err
 					}
@@ -67,6 +95,11 @@ func main() {
 					if err != nil {
 						return err
 					}
+					opt := &webeye.Options{
+						CustomConcurrency: int(cmd.Int("probe-threads")),
+						ContinueOnError:   cmd.Bool("continue-on-error"),
+						MinConfidence:     cmd.Float("min-confidence"),
+					}
 					table := tablewriter.NewWriter(os.Stdout)
 					table.SetHeader([]string{"target", "finger", "error"})
 					rowCh := make(chan []string, 10)
@@ -75,10 +108,11 @@ func main() {
 						swg.Add()
 						go func()  {
 							defer swg.Done()
-							res, err := webeye.GetWebFinger(context.Background(), target, *wfs)
+							res, err := webeye.GetWebFingerWithOptions(context.Background(), target, *wfs, opt)
+							res = finger.FilterResults(res, cmd.StringSlice("tag"), cmd.StringSlice("category"))
 							var targetFingers []string
 							for _, r := range res {
-								targetFingers = append(targetFingers, r.Name)
+								targetFingers = append(targetFingers, r.String())
 							}
 							var errText string
 							if err != nil {
@@ -102,6 +136,7 @@ func main() {
 					return nil
 				},
 			},
+			rulesCommand(),
 		},
     }
 
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/akkuman/webeye/finger"
+	"github.com/akkuman/webeye/req"
+	"github.com/akkuman/webeye/utils"
+	"github.com/urfave/cli/v3"
+)
+
+// rulesCommand 指纹规则相关的子命令
+func rulesCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "rules",
+		Usage: "manage fingerprint rules",
+		Commands: []*cli.Command{
+			{
+				Name:  "lint",
+				Usage: "report empty, duplicate, invalid and ignored fingerprint rules",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "template",
+						Usage:    "template for fingerprint (from: filepath or url(with http(s)://))",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Value: finger.FormatAuto,
+						Usage: "format of template (" + strings.Join(append([]string{finger.FormatAuto}, finger.Formats()...), ", ") + ")",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					issues, err := lintTemplate(cmd.String("template"), cmd.String("format"))
+					if err != nil {
+						return err
+					}
+					for _, issue := range issues {
+						fmt.Fprintln(os.Stdout, issue.String())
+					}
+					if finger.HasLintError(issues) {
+						return fmt.Errorf("found %d issues in %s", len(issues), cmd.String("template"))
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "test",
+				Usage: "run the match/not_match fixtures attached to fingerprint rules",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "template",
+						Usage:    "template for fingerprint (from: filepath or url(with http(s)://))",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Value: finger.FormatAuto,
+						Usage: "format of template (" + strings.Join(append([]string{finger.FormatAuto}, finger.Formats()...), ", ") + ")",
+					},
+					&cli.StringFlag{
+						Name:  "fixtures",
+						Usage: "base directory of fixture files (default: directory of local template)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return testTemplate(cmd.String("template"), cmd.String("format"), cmd.String("fixtures"))
+				},
+			},
+		},
+	}
+}
+
+// testTemplate 使用规则中的 fixture 用例测试指纹，有用例失败时返回错误
+func testTemplate(templateFileURL string, format string, fixtureDir string) error {
+	wfs, err := LoadFinger(templateFileURL, format)
+	if err != nil {
+		return err
+	}
+	if fixtureDir == "" && !strings.HasPrefix(templateFileURL, "http://") && !strings.HasPrefix(templateFileURL, "https://") {
+		fixtureDir = filepath.Dir(templateFileURL)
+	}
+	tester := &finger.RuleTester{BaseDir: fixtureDir, TitleFunc: req.ExtractTitle}
+	var total, failed int
+	for _, res := range tester.Run(wfs) {
+		total += res.Total
+		failed += len(res.Failures)
+		for _, f := range res.Failures {
+			fmt.Fprintf(os.Stdout, "[FAIL] %s (%s) %s\n", res.Name, res.Kind, f.String())
+		}
+	}
+	fmt.Fprintf(os.Stdout, "%d cases, %d failed\n", total, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cases failed in %s", failed, total, templateFileURL)
+	}
+	return nil
+}
+
+// lintTemplate 检查指纹文件，FingerprintHub 格式检查原始规则，其他格式检查解析后的指纹
+func lintTemplate(templateFileURL string, format string) ([]finger.LintIssue, error) {
+	content, err := utils.GetLocalFileOrWeb(templateFileURL)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" || format == finger.FormatAuto {
+		if format, err = finger.DetectFormat(string(content)); err != nil {
+			return nil, err
+		}
+	}
+	if format == finger.FormatFingerprintHub {
+		return finger.Validate(string(content))
+	}
+	wfs, err := finger.ParseWebFingerFormat(format, string(content))
+	if err != nil {
+		return nil, err
+	}
+	return finger.ValidateSystem(wfs), nil
+}
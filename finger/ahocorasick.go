@@ -0,0 +1,118 @@
+package finger
+
+import "strings"
+
+// keywordMatcher 基于 Aho–Corasick 的多模式关键词匹配自动机
+// 关键词统一转为小写，扫描一遍小写正文即可得到所有命中的关键词，避免逐个指纹调用 strings.Contains
+type keywordMatcher struct {
+	root     [256]int32 // 根节点的完整跳转表，根节点访问最频繁，单独展开
+	nodes    []acNode
+	ids      map[string]int32 // 原始关键词 -> 模式 id
+	patterns int
+}
+
+type acNode struct {
+	edges []acEdge
+	fail  int32
+	out   []int32 // 以当前节点结尾的模式 id（已合并失配链上的输出）
+}
+
+type acEdge struct {
+	b  byte
+	to int32
+}
+
+func (n *acNode) next(b byte) int32 {
+	for _, e := range n.edges {
+		if e.b == b {
+			return e.to
+		}
+	}
+	return -1
+}
+
+// newKeywordMatcher 使用关键词构建自动机，大小写不敏感
+// 空关键词任何文本都包含，不放入自动机，由调用方退化为 strings.Contains 处理
+func newKeywordMatcher(keywords []string) *keywordMatcher {
+	m := &keywordMatcher{
+		nodes: []acNode{{}},
+		ids:   make(map[string]int32),
+	}
+	lowerIDs := make(map[string]int32)
+	for _, keyword := range keywords {
+		if keyword == "" {
+			continue
+		}
+		if _, ok := m.ids[keyword]; ok {
+			continue
+		}
+		lower := strings.ToLower(keyword)
+		id, ok := lowerIDs[lower]
+		if !ok {
+			id = int32(m.patterns)
+			m.patterns++
+			lowerIDs[lower] = id
+			m.insert(lower, id)
+		}
+		m.ids[keyword] = id
+	}
+	m.build()
+	return m
+}
+
+func (m *keywordMatcher) insert(pattern string, id int32) {
+	var cur int32
+	for i := 0; i < len(pattern); i++ {
+		nxt := m.nodes[cur].next(pattern[i])
+		if nxt < 0 {
+			nxt = int32(len(m.nodes))
+			m.nodes = append(m.nodes, acNode{})
+			m.nodes[cur].edges = append(m.nodes[cur].edges, acEdge{pattern[i], nxt})
+		}
+		cur = nxt
+	}
+	m.nodes[cur].out = append(m.nodes[cur].out, id)
+}
+
+// build 广度优先计算失配指针，并展开根节点跳转表
+func (m *keywordMatcher) build() {
+	queue := make([]int32, 0, len(m.nodes))
+	for _, e := range m.nodes[0].edges {
+		m.root[e.b] = e.to
+		queue = append(queue, e.to)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, e := range m.nodes[cur].edges {
+			// 失配指针指向当前节点失配指针读入同一字节后的状态，深度一定更浅，已经计算完成
+			m.nodes[e.to].fail = m.step(m.nodes[cur].fail, e.b)
+			m.nodes[e.to].out = append(m.nodes[e.to].out, m.nodes[m.nodes[e.to].fail].out...)
+			queue = append(queue, e.to)
+		}
+	}
+}
+
+// step 从 state 读入一个字节后跳转到的状态
+func (m *keywordMatcher) step(state int32, b byte) int32 {
+	for state != 0 {
+		if nxt := m.nodes[state].next(b); nxt >= 0 {
+			return nxt
+		}
+		state = m.nodes[state].fail
+	}
+	return m.root[b]
+}
+
+// match 扫描小写文本，返回每个模式是否命中
+func (m *keywordMatcher) match(lowerText string) []bool {
+	hits := make([]bool, m.patterns)
+	var state int32
+	for i := 0; i < len(lowerText); i++ {
+		state = m.step(state, lowerText[i])
+		for _, id := range m.nodes[state].out {
+			hits[id] = true
+		}
+	}
+	return hits
+}
@@ -0,0 +1,153 @@
+package finger
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestKeywordMatcher(t *testing.T) {
+	keywords := []string{"he", "She", "his", "hers", "HERS", "ushers", "a", "中文", "x", ""}
+	m := newKeywordMatcher(keywords)
+	// 空关键词不放入自动机，由 containsKeyword 退化为 strings.Contains
+	if _, ok := m.ids[""]; ok {
+		t.Errorf("ids[\"\"] should not exist")
+	}
+	texts := []string{"", "ushers", "ahishers", "she", "中文内容 his", "zzz"}
+	for _, text := range texts {
+		hits := m.match(strings.ToLower(text))
+		resp := &Response{Body: []byte(text)}
+		resp.useKeywordMatcher(m)
+		for _, keyword := range keywords {
+			want := strings.Contains(strings.ToLower(text), strings.ToLower(keyword))
+			if id, ok := m.ids[keyword]; ok && hits[id] != want {
+				t.Errorf("match(%q)[%q] = %v; want %v", text, keyword, hits[id], want)
+			}
+			if got := resp.containsKeyword(keyword, ScopeBody, false); got != want {
+				t.Errorf("containsKeyword(%q, %q) = %v; want %v", text, keyword, got, want)
+			}
+		}
+	}
+}
+
+func TestKeywordMatcherRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randString := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = "abcAB"[r.Intn(5)]
+		}
+		return string(b)
+	}
+	keywords := make([]string, 200)
+	for i := range keywords {
+		keywords[i] = randString(1 + r.Intn(6))
+	}
+	m := newKeywordMatcher(keywords)
+	for i := 0; i < 50; i++ {
+		text := strings.ToLower(randString(r.Intn(300)))
+		hits := m.match(text)
+		for _, keyword := range keywords {
+			want := strings.Contains(text, strings.ToLower(keyword))
+			if got := hits[m.ids[keyword]]; got != want {
+				t.Fatalf("match(%q)[%q] = %v; want %v", text, keyword, got, want)
+			}
+		}
+	}
+}
+
+// benchmarkFingers 生成和 FingerprintHub 规模相当的首页指纹及一个约 100KB 的页面
+func benchmarkFingers(n int) (*WebFingerSystem, []byte) {
+	r := rand.New(rand.NewSource(1))
+	word := func() string {
+		b := make([]byte, 4+r.Intn(12))
+		for i := range b {
+			b[i] = byte('a' + r.Intn(26))
+		}
+		return string(b)
+	}
+	wfs := new(WebFingerSystem)
+	var hits []string
+	for i := 0; i < n; i++ {
+		wf := WebFinger{
+			Name:    fmt.Sprintf("finger-%d", i),
+			Request: RequestInfo{Path: "/", RequestMethod: "get"},
+			MatchRules: MatchRule{
+				Keyword: []string{word(), word()},
+			},
+			RootPath: "/",
+		}
+		if i%100 == 0 {
+			hits = append(hits, wf.MatchRules.Keyword...)
+		}
+		wfs.Indexs = append(wfs.Indexs, wf)
+	}
+	var body strings.Builder
+	body.WriteString("<html><head><title>Benchmark</title></head><body>")
+	for body.Len() < 100*1024 {
+		fmt.Fprintf(&body, "<div class=\"%s\">%s %s</div>\n", word(), word(), strings.ToUpper(word()))
+	}
+	body.WriteString(strings.Join(hits, " "))
+	body.WriteString("</body></html>")
+	return wfs, []byte(body.String())
+}
+
+// loopMatchIndex 引入自动机之前的关键词匹配方式：每条指纹都把响应体转为小写，再逐个关键词调用 strings.Contains
+func loopMatchIndex(wfs *WebFingerSystem, body []byte) (matched int) {
+	for i := range wfs.Indexs {
+		keywords := wfs.Indexs[i].MatchRules.Keyword
+		if len(keywords) == 0 {
+			continue
+		}
+		bodytext := strings.ToLower(string(body))
+		ok := true
+		for _, keyword := range keywords {
+			if !strings.Contains(bodytext, strings.ToLower(keyword)) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched++
+		}
+	}
+	return matched
+}
+
+func BenchmarkMatchIndex(b *testing.B) {
+	for _, n := range []int{1000, 5000} {
+		wfs, body := benchmarkFingers(n)
+		b.Run(fmt.Sprintf("Loop-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				loopMatchIndex(wfs, body)
+			}
+		})
+		b.Run(fmt.Sprintf("AhoCorasick-%d", n), func(b *testing.B) {
+			wfs.Build()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				wfs.MatchIndexResponse(&Response{Body: body, StatusCode: 200})
+			}
+		})
+	}
+}
+
+func TestMatchIndexAhoCorasick(t *testing.T) {
+	wfs, body := benchmarkFingers(1000)
+	wfs.keywords = nil
+	want := wfs.MatchIndexResponse(&Response{Body: body})
+	wfs.Build()
+	got := wfs.MatchIndexResponse(&Response{Body: body})
+	if len(got) != len(want) || len(got) == 0 {
+		t.Fatalf("len(MatchIndex) = %d; want %d", len(got), len(want))
+	}
+	if n := loopMatchIndex(wfs, body); n != len(got) {
+		t.Fatalf("loopMatchIndex = %d; want %d", n, len(got))
+	}
+	for i := range got {
+		if got[i].Name != want[i].Name {
+			t.Errorf("MatchIndex[%d] = %s; want %s", i, got[i].Name, want[i].Name)
+		}
+	}
+}
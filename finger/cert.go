@@ -0,0 +1,119 @@
+package finger
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+)
+
+// CertRule 匹配 TLS 证书链中的证书，所有指定的字段都需要在同一张证书上满足，证书链中任意一张证书满足即可
+// 文本字段为包含关系，不区分大小写；序列号和指纹需要完全相等
+type CertRule struct {
+	Subject      string `json:"subject,omitempty"`      // 主题，如 CN=FortiGate,O=Fortinet
+	Issuer       string `json:"issuer,omitempty"`       // 颁发者
+	Organization string `json:"organization,omitempty"` // 主题中的组织（O）
+	SAN          string `json:"san,omitempty"`          // 任意一个备用名称：域名、IP、邮箱、URI
+	Text         string `json:"text,omitempty"`         // 主题、颁发者、备用名称和序列号中的任意一个
+	// 序列号，十六进制（可以带冒号或 0x 前缀）或十进制
+	Serial string `json:"serial,omitempty"`
+	// 证书 DER 的 sha1 或 sha256 指纹，十六进制，可以带冒号
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+func (cr *CertRule) isEmpty() bool {
+	return *cr == CertRule{}
+}
+
+// match 返回第一张满足条件的证书的命中条件
+func (cr *CertRule) match(certs []x509.Certificate) ([]Condition, bool) {
+	for i := range certs {
+		if conds, ok := cr.matchCert(&certs[i]); ok {
+			return conds, true
+		}
+	}
+	return nil, false
+}
+
+func (cr *CertRule) matchCert(cert *x509.Certificate) ([]Condition, bool) {
+	var conds []Condition
+	check := func(field string, want string, values ...string) bool {
+		if want == "" {
+			return true
+		}
+		for _, v := range values {
+			if strings.Contains(strings.ToLower(v), strings.ToLower(want)) {
+				conds = append(conds, Condition{ConditionCert, field + ": " + v})
+				return true
+			}
+		}
+		return false
+	}
+	subject, issuer, sans := cert.Subject.String(), cert.Issuer.String(), certSANs(cert)
+	if !check("subject", cr.Subject, subject) ||
+		!check("issuer", cr.Issuer, issuer) ||
+		!check("organization", cr.Organization, cert.Subject.Organization...) ||
+		!check("san", cr.SAN, sans...) ||
+		!check("text", cr.Text, append([]string{subject, issuer, cert.SerialNumber.Text(16)}, sans...)...) {
+		return nil, false
+	}
+	if cr.Serial != "" {
+		if !serialEqual(cert, cr.Serial) {
+			return nil, false
+		}
+		conds = append(conds, Condition{ConditionCert, "serial: " + cert.SerialNumber.Text(16)})
+	}
+	if cr.Fingerprint != "" {
+		fp, ok := fingerprintEqual(cert, cr.Fingerprint)
+		if !ok {
+			return nil, false
+		}
+		conds = append(conds, Condition{ConditionCert, "fingerprint: " + fp})
+	}
+	return conds, true
+}
+
+// certSANs 返回证书的所有备用名称
+func certSANs(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
+
+// normalizeHex 去掉十六进制文本中的冒号、空格和 0x 前缀并转为小写
+func normalizeHex(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "0x")
+	return strings.NewReplacer(":", "", " ", "").Replace(s)
+}
+
+func serialEqual(cert *x509.Certificate, serial string) bool {
+	if cert.SerialNumber == nil {
+		return false
+	}
+	// 十六进制比较时忽略前导 0
+	if strings.TrimLeft(normalizeHex(serial), "0") == strings.TrimLeft(cert.SerialNumber.Text(16), "0") {
+		return true
+	}
+	return strings.TrimSpace(serial) == cert.SerialNumber.String()
+}
+
+// fingerprintEqual 比较 sha1 或 sha256 指纹，相等时返回证书的指纹
+func fingerprintEqual(cert *x509.Certificate, fingerprint string) (string, bool) {
+	fp := normalizeHex(fingerprint)
+	sha1Sum := sha1.Sum(cert.Raw)
+	sha256Sum := sha256.Sum256(cert.Raw)
+	for _, sum := range []string{hex.EncodeToString(sha1Sum[:]), hex.EncodeToString(sha256Sum[:])} {
+		if fp == sum {
+			return sum, true
+		}
+	}
+	return "", false
+}
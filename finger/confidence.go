@@ -0,0 +1,71 @@
+package finger
+
+// ConditionWeights 每种命中条件对置信度的贡献，取值范围 0~1
+// 单个条件的权重即只命中该条件时的置信度，多个条件按 1-Π(1-w) 累加
+var ConditionWeights = map[string]float64{
+	ConditionStatusCode:    0.1,
+	ConditionContentLength: 0.2,
+	ConditionBodyHash:      0.9,
+	ConditionHeader:        0.5,
+	ConditionHeaderRegex:   0.5,
+	ConditionKeyword:       0.4,
+	ConditionKeywordRegex:  0.5,
+	ConditionTitle:         0.5,
+	ConditionTitleRegex:    0.5,
+	ConditionCookie:        0.5,
+	ConditionCookieRegex:   0.5,
+	ConditionSelector:      0.5,
+	ConditionJSON:          0.5,
+	ConditionCert:          0.5,
+	ConditionFavicon:       0.6,
+	ConditionFaviconPHash:  0.5,
+}
+
+// KindWeights 命中方式本身对置信度的贡献，自定义请求命中了特定路径，比首页的同样条件更可信
+var KindWeights = map[string]float64{
+	MatchKindCustom: 0.3,
+}
+
+// impliedFactor 隐含指纹的置信度相对于隐含它的指纹的折扣
+const impliedFactor = 0.8
+
+// combineConfidence 合并多个相互独立的置信度：1-Π(1-c)
+func combineConfidence(values ...float64) float64 {
+	miss := 1.0
+	for _, c := range values {
+		miss *= 1 - min(max(c, 0), 1)
+	}
+	return 1 - miss
+}
+
+// evidenceConfidence 根据命中方式和命中条件计算一次命中的置信度
+func evidenceConfidence(kind string, conds []Condition) float64 {
+	values := []float64{KindWeights[kind]}
+	for _, c := range conds {
+		values = append(values, ConditionWeights[c.Type])
+	}
+	return combineConfidence(values...)
+}
+
+// resultConfidence 汇总所有命中证据的置信度，同一个指纹被多个规则或多个页面命中时置信度更高
+func resultConfidence(evidences []Evidence) float64 {
+	values := make([]float64, 0, len(evidences))
+	for _, e := range evidences {
+		values = append(values, e.Confidence)
+	}
+	return combineConfidence(values...)
+}
+
+// FilterConfidence 过滤掉置信度低于 minConfidence 的识别结果
+func FilterConfidence(results []WebFingerResult, minConfidence float64) []WebFingerResult {
+	if minConfidence <= 0 {
+		return results
+	}
+	var filtered []WebFingerResult
+	for _, r := range results {
+		if r.Confidence >= minConfidence {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
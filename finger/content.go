@@ -0,0 +1,88 @@
+package finger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lengthRange 闭区间的长度范围，max 为 -1 时没有上限
+type lengthRange struct {
+	min, max int
+}
+
+// ContentLengths 响应体长度条件，为空时匹配任意长度，满足任意一个范围即可
+// 长度为实际读取到的响应体字节数，而不是 Content-Length 响应头
+type ContentLengths struct {
+	ranges []lengthRange
+}
+
+// ParseContentLengths 解析响应体长度条件，每一项可以是 1024、100-200、100-（至少 100）、-200（最多 200）
+func ParseContentLengths(values ...string) (cl ContentLengths, err error) {
+	for _, v := range values {
+		lr, err := parseLengthRange(strings.TrimSpace(v))
+		if err != nil {
+			return cl, err
+		}
+		cl.ranges = append(cl.ranges, lr)
+	}
+	return cl, nil
+}
+
+func parseLengthRange(s string) (lr lengthRange, err error) {
+	parse := func(v string, empty int) (int, error) {
+		if v = strings.TrimSpace(v); v == "" {
+			return empty, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("响应体长度 %s 不合法", s)
+		}
+		return n, nil
+	}
+	lo, hi, ok := strings.Cut(s, "-")
+	if !ok {
+		n, err := parse(s, -1)
+		if err != nil || n < 0 {
+			return lr, fmt.Errorf("响应体长度 %s 不合法", s)
+		}
+		return lengthRange{n, n}, nil
+	}
+	if lr.min, err = parse(lo, 0); err != nil {
+		return lr, err
+	}
+	if lr.max, err = parse(hi, -1); err != nil {
+		return lr, err
+	}
+	if lr.max >= 0 && lr.min > lr.max {
+		return lr, fmt.Errorf("响应体长度范围 %s 不合法", s)
+	}
+	return lr, nil
+}
+
+// IsEmpty 判断是否没有任何长度条件
+func (cl ContentLengths) IsEmpty() bool {
+	return len(cl.ranges) == 0
+}
+
+// Match 判断长度是否在任意一个范围内
+func (cl ContentLengths) Match(length int) bool {
+	for _, lr := range cl.ranges {
+		if length >= lr.min && (lr.max < 0 || length <= lr.max) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentLengthRaw 指纹文件中的响应体长度条件，可以是数字、字符串或两者混合的列表，如 [0, "100-200", "4096-"]
+type ContentLengthRaw []string
+
+func (clr *ContentLengthRaw) UnmarshalJSON(data []byte) error {
+	items, err := unmarshalIntStringList(data)
+	if err != nil {
+		return fmt.Errorf("响应体长度%w", err)
+	}
+	*clr = items
+	return nil
+}
@@ -0,0 +1,58 @@
+package finger
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// CustomRequest 请求签名相同的一组自定义请求指纹
+// 对每个目标只需要发送一次请求，组内所有指纹都使用这一次的响应进行匹配
+type CustomRequest struct {
+	Request RequestInfo // 组内第一个指纹的请求
+	Fingers []WebFinger // 共享该请求的指纹
+}
+
+// Match 使用请求的响应匹配组内所有指纹
+func (cr *CustomRequest) Match(resp *Response) []WebFingerResult {
+	var res []WebFingerResult
+	for i := range cr.Fingers {
+		if r, ok := cr.Fingers[i].Match(resp); ok {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// Signature 返回请求签名，请求方法、路径、请求头和请求体都相同的请求签名相同
+// 请求方法为空时等同于 GET，请求头名称不区分大小写
+func (ri *RequestInfo) Signature() string {
+	method := strings.ToUpper(ri.RequestMethod)
+	if method == "" {
+		method = http.MethodGet
+	}
+	headers := make(map[string]string, len(ri.RequestHeader))
+	for k, v := range ri.RequestHeader {
+		headers[http.CanonicalHeaderKey(k)] = v
+	}
+	// map 序列化时键是有序的，可以直接作为签名
+	d, _ := json.Marshal([]any{method, ri.Path, headers, ri.RequestData})
+	return string(d)
+}
+
+// groupCustomRequests 按请求签名对自定义请求指纹分组，分组顺序和指纹首次出现的顺序一致
+func groupCustomRequests(fingers []WebFinger) []CustomRequest {
+	var groups []CustomRequest
+	index := make(map[string]int)
+	for _, wf := range fingers {
+		sig := wf.Request.Signature()
+		i, ok := index[sig]
+		if !ok {
+			i = len(groups)
+			index[sig] = i
+			groups = append(groups, CustomRequest{Request: wf.Request})
+		}
+		groups[i].Fingers = append(groups[i].Fingers, wf)
+	}
+	return groups
+}
@@ -0,0 +1,87 @@
+package finger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EHoleFinger EHole finger.json 中的一条指纹
+type EHoleFinger struct {
+	CMS      string   `json:"cms"`
+	Method   string   `json:"method"`   // keyword、regula（正则）、faviconhash
+	Location string   `json:"location"` // body、header、title
+	Keyword  []string `json:"keyword"`  // 所有关键词都需要命中
+}
+
+func (ef *EHoleFinger) toWebFinger() (*WebFinger, error) {
+	wf := &WebFinger{
+		Name:     ef.CMS,
+		Request:  RequestInfo{Path: "/", RequestMethod: "get"},
+		RootPath: "/",
+	}
+	location := strings.ToLower(ef.Location)
+	switch location {
+	case "", "body", "header", "title":
+	default:
+		return nil, fmt.Errorf("不支持的 location %s", ef.Location)
+	}
+	switch strings.ToLower(ef.Method) {
+	case "keyword":
+		if location == "title" {
+			wf.MatchRules.Title = ef.Keyword
+			break
+		}
+		wf.MatchRules.Keyword = ef.Keyword
+	case "regula":
+		for _, expr := range ef.Keyword {
+			re, err := compileRegex(expr)
+			if err != nil {
+				return nil, fmt.Errorf("关键词正则不合法: %w", err)
+			}
+			if location == "title" {
+				wf.MatchRules.TitleRegex = append(wf.MatchRules.TitleRegex, re)
+			} else {
+				wf.MatchRules.KeywordRegex = append(wf.MatchRules.KeywordRegex, re)
+			}
+		}
+	case "faviconhash":
+		wf.MatchRules.FaviconHash = ef.Keyword
+		return wf, nil
+	default:
+		return nil, fmt.Errorf("不支持的 method %s", ef.Method)
+	}
+	if location == "header" {
+		wf.MatchRules.Scope = ScopeHeader
+	}
+	return wf, nil
+}
+
+func isEHole(content string) bool {
+	_, ok := jsonObject(content)["fingerprint"]
+	return ok
+}
+
+// ParseEHole 解析 EHole 格式的指纹（finger.json）
+func ParseEHole(content string) (*WebFingerSystem, error) {
+	var file struct {
+		Fingerprint []EHoleFinger `json:"fingerprint"`
+	}
+	if err := json.Unmarshal([]byte(content), &file); err != nil {
+		return nil, err
+	}
+	wfs := new(WebFingerSystem)
+	for i := range file.Fingerprint {
+		wf, err := file.Fingerprint[i].toWebFinger()
+		if err != nil {
+			return nil, fmt.Errorf("指纹 %s %w", file.Fingerprint[i].CMS, err)
+		}
+		if wf.IsFavicon() {
+			wfs.Favicons = append(wfs.Favicons, *wf)
+		} else {
+			wfs.Indexs = append(wfs.Indexs, *wf)
+		}
+	}
+	wfs.Build()
+	return wfs, nil
+}
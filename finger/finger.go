@@ -3,12 +3,18 @@ package finger
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/akkuman/webeye/utils"
 	mapset "github.com/deckarep/golang-set/v2"
 )
 
+// DefaultPHashDistance 图标感知哈希默认允许的最大汉明距离，64 位中不超过 10 位不同时认为是同一个图标
+const DefaultPHashDistance = 10
+
 type RequestInfo struct {
 	Path          string            `json:"path"`            // 请求路径
 	RequestMethod string            `json:"request_method"`  // 请求方法，默认 GET
@@ -16,92 +22,166 @@ type RequestInfo struct {
 	RequestData   []byte            `json:"request_data"`    // 请求数据
 }
 
-type MatchRule struct {
-	StatusCode  int               `json:"status_code"`  // 匹配状态码
-	FaviconHash []string          `json:"favicon_hash"` // 匹配图标 hash，一个匹配到了就算命中
-	Headers     map[string]string `json:"headers"`      // 匹配全球头，读取键，匹配值，如果值为*或者空，只匹配键
-	Keyword     []string          `json:"keyword"`      // 匹配关键词
-}
-
 type WebFinger struct {
+	Metadata               // 资产信息
 	Name       string      `json:"name"`        // 指纹名称
 	Priority   int         `json:"priority"`    // 指纹优先度
 	Request    RequestInfo `json:"request"`     // 自定义请求
 	MatchRules MatchRule   `json:"match_rules"` // 匹配规则
 	RootPath   string      `json:"root_path"`   // 站点根路径，默认为 /
+	// 版本提取器，按顺序提取，使用第一个提取到的版本
+	Version  []VersionExtractor `json:"version"`
+	Implies  []string           `json:"implies"`  // 命中后同时认为存在的指纹名称
+	Excludes []string           `json:"excludes"` // 命中后需要排除的指纹名称，如具体的 OA 系统排除其使用的通用框架
+	// 命中时的置信度，0~1，为 0 时根据命中方式和命中条件的权重计算，见 ConditionWeights
+	Confidence float64   `json:"confidence"`
+	Tests      RuleTests `json:"tests"` // 自测用例，见 RuleTester
 }
 
 func (wf *WebFinger) IsIndex() bool {
-	return wf.Request.Path == "/" && len(wf.Request.RequestHeader) == 0 && strings.ToLower(wf.Request.RequestMethod) == "get" && len(wf.Request.RequestData) == 0 && len(wf.MatchRules.FaviconHash) == 0
+	return wf.Request.Path == "/" && len(wf.Request.RequestHeader) == 0 && strings.ToLower(wf.Request.RequestMethod) == "get" && len(wf.Request.RequestData) == 0 && !wf.IsFavicon()
 }
 
 func (wf *WebFinger) IsFavicon() bool {
-	return len(wf.MatchRules.FaviconHash) > 0
+	return len(wf.MatchRules.FaviconHash) > 0 || len(wf.MatchRules.FaviconPHash) > 0
 }
 
 func (wf *WebFinger) IsCustom() bool {
 	return wf.Request.Path != "/" || strings.ToLower(wf.Request.RequestMethod) != "get" || len(wf.Request.RequestHeader) != 0 || len(wf.Request.RequestData) != 0
 }
 
-// MatchKeyWord 匹配指纹判断是否命中
+// MatchKind 返回指纹的命中方式，和 ParseWebFinger 中的分类保持一致
+func (wf *WebFinger) MatchKind() string {
+	if wf.IsIndex() {
+		return MatchKindIndex
+	} else if wf.IsFavicon() {
+		return MatchKindFavicon
+	}
+	return MatchKindCustom
+}
+
+// MatchKeyWord 匹配指纹判断是否命中，headers 为 HTTPHeadersToMap 得到的响应头
 func (wf *WebFinger) MatchKeyWord(data []byte, headers map[string]string, status_code int) bool {
-	// 匹配状态码，指纹规则中有状态码，但是和传进来的不匹配
-	if wf.MatchRules.StatusCode != 0 && wf.MatchRules.StatusCode != status_code {
-		return false
-	}
-	// 匹配 header，指纹规则中有请求头，但是没有找到键
-	for k, v := range wf.MatchRules.Headers {
-		if hk, ok := headers[strings.ToLower(k)]; ok {
-			// *时只匹配键
-			if v != "*" && !strings.Contains(hk, strings.ToLower(v)) {
-				return false
-			}
-		} else {
-			return false
+	header := make(http.Header, len(headers))
+	for k, v := range headers {
+		header.Set(k, v)
+	}
+	return wf.MatchKeyWordResponse(&Response{Body: data, Header: header, StatusCode: status_code})
+}
+
+// MatchKeyWordResponse 使用完整的响应匹配指纹判断是否命中
+func (wf *WebFinger) MatchKeyWordResponse(resp *Response) bool {
+	_, ok := wf.MatchRules.match(resp)
+	return ok
+}
+
+// Match 匹配指纹，命中时返回附带版本和命中证据的识别结果
+// favicon 指纹只匹配图标 hash，其余指纹匹配 MatchRules
+func (wf *WebFinger) Match(resp *Response) (WebFingerResult, bool) {
+	var conds []Condition
+	if wf.IsFavicon() {
+		if conds = wf.matchFavicons(resp); len(conds) == 0 {
+			return WebFingerResult{}, false
 		}
+	} else {
+		var ok bool
+		if conds, ok = wf.MatchRules.match(resp); !ok {
+			return WebFingerResult{}, false
+		}
+	}
+	res := NewWebFingerResult(*wf)
+	res.Version = wf.ExtractVersion(resp)
+	kind := wf.MatchKind()
+	confidence := wf.Confidence
+	if confidence == 0 {
+		confidence = evidenceConfidence(kind, conds)
+	}
+	res.Evidences = []Evidence{{
+		URL:        resp.URL,
+		Kind:       kind,
+		Conditions: conds,
+		Confidence: confidence,
+	}}
+	res.Confidence = confidence
+	return res, true
+}
+
+// ExtractVersion 从响应中提取版本号，未配置或未提取到时返回空字符串
+func (wf *WebFinger) ExtractVersion(resp *Response) string {
+	for i := range wf.Version {
+		if v := wf.Version[i].Extract(resp); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// matchFavicons 匹配图标 hash 和感知哈希，返回命中的条件
+func (wf *WebFinger) matchFavicons(resp *Response) (conds []Condition) {
+	for _, hash := range wf.matchFavicon(resp.Favicons) {
+		conds = append(conds, Condition{ConditionFavicon, hash})
+	}
+	for _, hash := range wf.matchFaviconPHash(resp.FaviconPHashes) {
+		conds = append(conds, Condition{ConditionFaviconPHash, hash})
+	}
+	return conds
+}
+
+// matchFaviconPHash 返回和指纹中任意一个感知哈希的汉明距离不超过阈值的图标感知哈希，响应中无法解析的值会被忽略
+func (wf *WebFinger) matchFaviconPHash(phashes []string) (matched []string) {
+	if len(wf.MatchRules.FaviconPHash) == 0 {
+		return nil
 	}
-	// 匹配正文
-	// 提前判断防止 []byte->string 的转化
-	if len(wf.MatchRules.Keyword) != 0 {
-		bodytext := strings.ToLower(string(data))
-		for _, keyword := range wf.MatchRules.Keyword {
-			if !strings.Contains(bodytext, strings.ToLower(keyword)) {
-				return false
+	for _, s := range phashes {
+		hash, err := utils.ParsePHash(s)
+		if err != nil {
+			continue
+		}
+		for _, want := range wf.MatchRules.FaviconPHash {
+			if utils.HammingDistance(hash, want) <= wf.MatchRules.FaviconPHashDistance {
+				matched = append(matched, s)
+				break
 			}
 		}
 	}
-	return true
+	return matched
 }
 
 // MatchFavicon 匹配图标指纹，如果图标或图标指纹不存在，则返回 false，只有当有值并且匹配时，才返回 true
+// 只比较 md5 和 mmh3，感知哈希见 Match
 func (wf *WebFinger) MatchFavicon(favicons []string) bool {
+	return len(wf.matchFavicon(favicons)) > 0
+}
+
+// matchFavicon 返回命中的图标 hash
+func (wf *WebFinger) matchFavicon(favicons []string) []string {
 	// 匹配图标
 	if len(favicons) == 0 || len(wf.MatchRules.FaviconHash) == 0 {
-		return false
+		return nil
 	}
-	favicon_hash_set := mapset.NewSet(favicons...)
-	if len(wf.MatchRules.FaviconHash) > 0 {
-		// 存在 favicon 指纹的情况下，指纹中的 iconhash 没有一个匹配到，则指纹匹配失败
-		favicon_set := mapset.NewSet(wf.MatchRules.FaviconHash...)
-		if len(favicon_set.Intersect(favicon_hash_set).ToSlice()) == 0 {
-			return false
-		}
-	}
-	return true
+	// 存在 favicon 指纹的情况下，指纹中的 iconhash 没有一个匹配到，则指纹匹配失败
+	favicon_set := mapset.NewSet(wf.MatchRules.FaviconHash...)
+	return favicon_set.Intersect(mapset.NewSet(favicons...)).ToSlice()
 }
 
 type WebFingerRaw struct {
-	Name          string            `json:"name"`
-	Path          string            `json:"path"`
-	StatusCode    int               `json:"status_code"`
-	Headers       map[string]string `json:"headers"`
-	Keyword       []string          `json:"keyword"`
-	Priority      int               `json:"priority"`
-	RequestMethod string            `json:"request_method"`
-	RequestHeader map[string]string `json:"request_headers"`
-	RequestData   string            `json:"request_data"` // base64 编码后的请求体
-	FaviconHash   []string          `json:"favicon_hash"`
-	RootPath      string            `json:"root_path"` // 站点根路径，默认为 /
+	MatchRuleRaw                        // 匹配条件，和 name 等字段处于同一层级
+	Metadata                            // 资产信息，和 name 等字段处于同一层级
+	Name          string                `json:"name"`
+	Path          string                `json:"path"`
+	Priority      int                   `json:"priority"`
+	RequestMethod string                `json:"request_method"`
+	RequestHeader map[string]string     `json:"request_headers"`
+	RequestData   string                `json:"request_data"` // base64 编码后的请求体
+	FaviconHash   []string              `json:"favicon_hash"`
+	FaviconPHash  []string              `json:"favicon_phash"`          // 图标感知哈希，用于匹配重新编码或缩放过的图标
+	PHashDistance *int                  `json:"favicon_phash_distance"` // 感知哈希允许的最大汉明距离，不填时为 DefaultPHashDistance，0 表示完全相同
+	RootPath      string                `json:"root_path"`              // 站点根路径，默认为 /
+	Version       []VersionExtractorRaw `json:"version"`                // 版本提取器
+	Implies       []string              `json:"implies"`                // 命中后同时认为存在的指纹名称
+	Excludes      []string              `json:"excludes"`               // 命中后需要排除的指纹名称
+	Confidence    float64               `json:"confidence"`             // 命中时的置信度，0~1，不填时自动计算
+	Tests         RuleTests             `json:"tests"`                  // 自测用例
 }
 
 // json 转为首页，特殊路径和图标 hash 指纹
@@ -132,18 +212,52 @@ func (wfr *WebFingerRaw) toWebFinger() (wf *WebFinger, err error) {
 		RequestHeader: wfr.RequestHeader,
 		RequestData:   reqData,
 	}
-	match_rules := MatchRule{
-		Keyword:     wfr.Keyword,
-		Headers:     lowerMap(wfr.Headers),
-		FaviconHash: wfr.FaviconHash,
-		StatusCode:  wfr.StatusCode,
+	match_rules, err := wfr.MatchRuleRaw.toMatchRule()
+	if err != nil {
+		return nil, fmt.Errorf("指纹 %s %w", wfr.Name, err)
+	}
+	match_rules.FaviconHash = wfr.FaviconHash
+	for _, h := range wfr.FaviconPHash {
+		phash, err := utils.ParsePHash(h)
+		if err != nil {
+			return nil, fmt.Errorf("指纹 %s %w", wfr.Name, err)
+		}
+		match_rules.FaviconPHash = append(match_rules.FaviconPHash, phash)
+	}
+	match_rules.FaviconPHashDistance = DefaultPHashDistance
+	if wfr.PHashDistance != nil {
+		if *wfr.PHashDistance < 0 || *wfr.PHashDistance > 64 {
+			return nil, fmt.Errorf("指纹 %s 的 favicon_phash_distance 需要在 0~64 之间", wfr.Name)
+		}
+		match_rules.FaviconPHashDistance = *wfr.PHashDistance
+	}
+	metadata := wfr.Metadata
+	if err = metadata.normalize(); err != nil {
+		return nil, fmt.Errorf("指纹 %s %w", wfr.Name, err)
+	}
+	if wfr.Confidence < 0 || wfr.Confidence > 1 {
+		return nil, fmt.Errorf("指纹 %s 的 confidence 需要在 0~1 之间", wfr.Name)
+	}
+	var versions []VersionExtractor
+	for i := range wfr.Version {
+		ve, err := wfr.Version[i].toVersionExtractor()
+		if err != nil {
+			return nil, fmt.Errorf("指纹 %s %w", wfr.Name, err)
+		}
+		versions = append(versions, ve)
 	}
 	wf = &WebFinger{
+		Metadata:   metadata,
 		Name:       wfr.Name,
 		Priority:   wfr.Priority,
 		Request:    request,
 		MatchRules: match_rules,
 		RootPath:   rootPath,
+		Version:    versions,
+		Implies:    wfr.Implies,
+		Excludes:   wfr.Excludes,
+		Confidence: wfr.Confidence,
+		Tests:      wfr.Tests,
 	}
 	return
 }
@@ -152,6 +266,154 @@ type WebFingerSystem struct {
 	Indexs     []WebFinger // 首页请求指纹
 	CustomReqs []WebFinger // 自定义请求指纹
 	Favicons   []WebFinger // favicon 指纹
+	Relations  []WebFinger // 没有可用匹配规则的指纹，只参与 implies、excludes 和资产信息的汇总
+	Warnings   []string    // 解析时跳过的规则及原因，如不支持的 nuclei 模板
+
+	keywords     *keywordMatcher     // 所有关键词构建的自动机
+	customGroups []CustomRequest     // 按请求签名分组的自定义请求指纹
+	implies      map[string][]string // 指纹名称 -> 隐含的指纹名称
+	excludes     map[string][]string // 指纹名称 -> 需要排除的指纹名称
+	priorities   map[string]int      // 指纹名称 -> 同名指纹中最高的优先度
+	metadata     map[string]Metadata // 指纹名称 -> 同名指纹合并后的资产信息
+}
+
+// Build 预处理指纹，构建多模式关键词匹配自动机，并按请求签名对自定义请求指纹分组
+// ParseWebFinger 会自动调用，手动构造或修改指纹后需要重新调用，未调用时退化为逐个关键词匹配
+func (wfs *WebFingerSystem) Build() {
+	wfs.customGroups = groupCustomRequests(wfs.CustomReqs)
+	var keywords []string
+	for _, fingers := range [][]WebFinger{wfs.Indexs, wfs.CustomReqs} {
+		for i := range fingers {
+			keywords = fingers[i].MatchRules.keywords(keywords)
+		}
+	}
+	wfs.keywords = newKeywordMatcher(keywords)
+	wfs.implies, wfs.excludes, wfs.priorities = wfs.collectRelations()
+	wfs.metadata = wfs.collectMetadata()
+}
+
+// collectRelations 汇总同名指纹的 implies、excludes 和最高优先度
+func (wfs *WebFingerSystem) collectRelations() (implies, excludes map[string][]string, priorities map[string]int) {
+	implies = make(map[string][]string)
+	excludes = make(map[string][]string)
+	priorities = make(map[string]int)
+	for _, fingers := range [][]WebFinger{wfs.Indexs, wfs.CustomReqs, wfs.Favicons, wfs.Relations} {
+		for _, wf := range fingers {
+			for _, name := range wf.Implies {
+				if !slices.Contains(implies[wf.Name], name) {
+					implies[wf.Name] = append(implies[wf.Name], name)
+				}
+			}
+			for _, name := range wf.Excludes {
+				if !slices.Contains(excludes[wf.Name], name) {
+					excludes[wf.Name] = append(excludes[wf.Name], name)
+				}
+			}
+			if p, ok := priorities[wf.Name]; !ok || wf.Priority > p {
+				priorities[wf.Name] = wf.Priority
+			}
+		}
+	}
+	return
+}
+
+// collectMetadata 汇总同名指纹的资产信息
+func (wfs *WebFingerSystem) collectMetadata() map[string]Metadata {
+	metadata := make(map[string]Metadata)
+	for _, fingers := range [][]WebFinger{wfs.Indexs, wfs.CustomReqs, wfs.Favicons, wfs.Relations} {
+		for _, wf := range fingers {
+			m := metadata[wf.Name]
+			m.merge(wf.Metadata)
+			metadata[wf.Name] = m
+		}
+	}
+	return metadata
+}
+
+// Resolve 对识别结果做后处理：合并同名结果，先在直接命中的指纹之间按 excludes 排除，再从保留的结果递归补充 implies 隐含的指纹并再次排除，最后按优先度和置信度排序
+// 推导来源都已被排除的隐含指纹会被删除
+// 隐含的指纹和隐含它的指纹处于同一个站点路径下，并使用同名指纹规则中的优先度和资产信息，置信度在隐含它的指纹的基础上打折
+// excludes 按优先度从高到低生效，已经被排除的指纹不再排除其他指纹，互相排除时保留优先度高的一方
+func (wfs *WebFingerSystem) Resolve(results []WebFingerResult) []WebFingerResult {
+	implies, excludes, priorities, metadata := wfs.implies, wfs.excludes, wfs.priorities, wfs.metadata
+	if implies == nil {
+		implies, excludes, priorities = wfs.collectRelations()
+	}
+	if metadata == nil {
+		metadata = wfs.collectMetadata()
+	}
+	// 先在直接命中的指纹之间排除，被排除的指纹不再推导隐含指纹
+	results = applyExcludes(MergeResults(results), excludes)
+	for i := 0; i < len(results); i++ {
+		for _, name := range implies[results[i].Name] {
+			implied := WebFingerResult{
+				Metadata: metadata[name].clone(),
+				Name:     name,
+				RootPath: results[i].RootPath,
+				Priority: priorities[name],
+				Evidences: []Evidence{{
+					Kind:       MatchKindImplied,
+					Conditions: []Condition{{ConditionImpliedBy, results[i].Name}},
+					Confidence: results[i].Confidence * impliedFactor,
+				}},
+			}
+			// 合并后已存在的结果不会重复追加，保证循环能够结束
+			results = MergeResults(append(results, implied))
+		}
+	}
+	return pruneImplied(applyExcludes(results, excludes))
+}
+
+// applyExcludes 按优先度排序并应用 excludes，优先度更高或相同且先出现的指纹生效
+func applyExcludes(results []WebFingerResult, excludes map[string][]string) []WebFingerResult {
+	SortResults(results)
+	// 指纹 -> 排除它的指纹中最高的优先度
+	excluded := make(map[resultKey]int)
+	resolved := results[:0]
+	for _, r := range results {
+		if _, ok := excluded[resultKey{r.Name, r.RootPath}]; ok {
+			continue
+		}
+		for _, name := range excludes[r.Name] {
+			key := resultKey{name, r.RootPath}
+			if p, ok := excluded[key]; name != r.Name && (!ok || r.Priority > p) {
+				excluded[key] = r.Priority
+			}
+		}
+		resolved = append(resolved, r)
+	}
+	// 结果已按优先度排序，已保留的指纹只可能被优先度相同、后出现的指纹排除
+	return slices.DeleteFunc(resolved, func(r WebFingerResult) bool {
+		p, ok := excluded[resultKey{r.Name, r.RootPath}]
+		return ok && p >= r.Priority
+	})
+}
+
+// pruneImplied 删除只由隐含推导得到、但推导它的指纹都已被排除的结果，直到没有可删除的结果
+func pruneImplied(results []WebFingerResult) []WebFingerResult {
+	for {
+		present := make(map[resultKey]bool, len(results))
+		for _, r := range results {
+			present[resultKey{r.Name, r.RootPath}] = true
+		}
+		orphan := func(r WebFingerResult) bool {
+			for _, e := range r.Evidences {
+				if e.Kind != MatchKindImplied {
+					return false
+				}
+				for _, c := range e.Conditions {
+					if c.Type == ConditionImpliedBy && present[resultKey{c.Value, r.RootPath}] {
+						return false
+					}
+				}
+			}
+			return true
+		}
+		n := len(results)
+		if results = slices.DeleteFunc(results, orphan); len(results) == n {
+			return results
+		}
+	}
 }
 
 // ParseWebFinger 解析 web 指纹，传入一个列表（json）
@@ -175,38 +437,50 @@ func ParseWebFinger(content string) (*WebFingerSystem, error) {
 			wfs.CustomReqs = append(wfs.CustomReqs, *wf)
 		}
 	}
+	wfs.Build()
 	return wfs, nil
 }
 
-type WebFingerResult struct {
-	Name string
-	RootPath string
+// 匹配首页和 favicon 指纹
+func (wfs *WebFingerSystem) MatchIndex(data []byte, headers http.Header, statusCode int, favicons []string) []WebFingerResult {
+	return wfs.MatchIndexResponse(&Response{Body: data, Header: headers, StatusCode: statusCode, Favicons: favicons})
 }
 
-func NewWebFingerResult(wf WebFinger) WebFingerResult {
-	return WebFingerResult{
-		Name: wf.Name,
-		RootPath: wf.RootPath,
+// MatchIndexResponse 使用完整的响应匹配首页和 favicon 指纹
+func (wfs *WebFingerSystem) MatchIndexResponse(resp *Response) []WebFingerResult {
+	var res []WebFingerResult
+	if wfs.keywords != nil {
+		resp.useKeywordMatcher(wfs.keywords)
 	}
-}
-
-// 匹配首页和 favicon 指纹
-func (wfs *WebFingerSystem) MatchIndex(data []byte, headers http.Header, statusCode int, favicons []string) []WebFingerResult {
-	res := mapset.NewSet[WebFingerResult]()
-	headerMap := HTTPHeadersToMap(headers)
 	// 首页匹配
-	for _, f := range wfs.Indexs {
-		if f.MatchKeyWord(data, headerMap, statusCode) {
-			res.Add(NewWebFingerResult(f))
+	for i := range wfs.Indexs {
+		if r, ok := wfs.Indexs[i].Match(resp); ok {
+			res = append(res, r)
 		}
 	}
 	// favicon 匹配
-	for _, f := range wfs.Favicons {
-		if f.MatchFavicon(favicons) {
-			res.Add(NewWebFingerResult(f))
+	for i := range wfs.Favicons {
+		if r, ok := wfs.Favicons[i].Match(resp); ok {
+			res = append(res, r)
 		}
 	}
-	return res.ToSlice()
+	return MergeResults(res)
+}
+
+// CustomRequests 返回按请求签名分组的自定义请求指纹，每组只需要请求一次
+func (wfs *WebFingerSystem) CustomRequests() []CustomRequest {
+	if wfs.customGroups == nil && len(wfs.CustomReqs) != 0 {
+		return groupCustomRequests(wfs.CustomReqs)
+	}
+	return wfs.customGroups
+}
+
+// MatchCustom 使用自定义请求的响应匹配请求组内的指纹
+func (wfs *WebFingerSystem) MatchCustom(cr *CustomRequest, resp *Response) []WebFingerResult {
+	if wfs.keywords != nil {
+		resp.useKeywordMatcher(wfs.keywords)
+	}
+	return cr.Match(resp)
 }
 
 // Count 返回所有的指纹数量
@@ -1,6 +1,23 @@
 package finger
 
-import "testing"
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akkuman/webeye/utils"
+	"github.com/go-test/deep"
+)
 
 func TestMatchFavicon(t *testing.T) {
 	wf := WebFinger{
@@ -17,4 +34,679 @@ func TestMatchFavicon(t *testing.T) {
 		t.Error("wf.MatchFavicon([]string{}) == true")
 		return
 	}
-}
\ No newline at end of file
+}
+
+func TestMatchFaviconPHash(t *testing.T) {
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "favicon_phash": ["f0e4c2d3b1a09080"], "name": "default"},
+		{"path": "/", "favicon_phash": ["f0e4c2d3b1a09080"], "favicon_phash_distance": 2, "name": "strict"},
+		{"path": "/", "favicon_hash": ["116323821"], "favicon_phash": ["0000000000000000"], "name": "both"},
+		{"path": "/", "favicon_phash": ["f0e4c2d3b1a09080"], "favicon_phash_distance": 0, "name": "exact"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wfs.Favicons) != 4 || len(wfs.Indexs) != 0 {
+		t.Fatalf("len(wfs.Favicons) = %d, len(wfs.Indexs) = %d; want 4, 0", len(wfs.Favicons), len(wfs.Indexs))
+	}
+	tests := []struct {
+		resp *Response
+		want []string
+	}{
+		{&Response{FaviconPHashes: []string{"f0e4c2d3b1a09080"}}, []string{"default", "strict", "exact"}},
+		// 相差 1 位
+		{&Response{FaviconPHashes: []string{"f0e4c2d3b1a09081"}}, []string{"default", "strict"}},
+		// 相差 4 位
+		{&Response{FaviconPHashes: []string{"f0e4c2d3b1a0908f"}}, []string{"default"}},
+		{&Response{FaviconPHashes: []string{"0f1b3d2c4e5f6f7f"}}, nil},
+		{&Response{FaviconPHashes: []string{"invalid"}, Favicons: []string{"116323821"}}, []string{"both"}},
+	}
+	for i, tt := range tests {
+		var got []string
+		for _, result := range wfs.MatchIndexResponse(tt.resp) {
+			got = append(got, result.Name)
+		}
+		if diff := deep.Equal(got, tt.want); diff != nil {
+			t.Errorf("#%d: %v", i, diff)
+		}
+	}
+	results := wfs.MatchIndexResponse(&Response{FaviconPHashes: []string{"f0e4c2d3b1a09081"}})
+	want := []Condition{{ConditionFaviconPHash, "f0e4c2d3b1a09081"}}
+	if diff := deep.Equal(results[0].Evidences[0].Conditions, want); diff != nil {
+		t.Error(diff)
+	}
+	for _, rule := range []string{
+		`{"path": "/", "favicon_phash": ["f0e4"], "name": "bad"}`,
+		`{"path": "/", "favicon_phash": ["f0e4c2d3b1a09080"], "favicon_phash_distance": 65, "name": "bad"}`,
+		`{"path": "/", "favicon_phash": ["f0e4c2d3b1a09080"], "favicon_phash_distance": -1, "name": "bad"}`,
+	} {
+		if _, err := ParseWebFinger("[" + rule + "]"); err == nil {
+			t.Errorf("%s: want error", rule)
+		}
+	}
+}
+
+func TestMatchKeyWordRegex(t *testing.T) {
+	wfs, err := ParseWebFinger(`[{
+		"path": "/",
+		"request_method": "get",
+		"keyword": ["powered by"],
+		"keyword_regex": ["demo-app v\\d+\\.\\d+"],
+		"headers_regex": {"Server": "^demo/\\d+$"},
+		"name": "demo-app"
+	}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wfs.Indexs) != 1 {
+		t.Fatalf("len(wfs.Indexs) = %d; want 1", len(wfs.Indexs))
+	}
+	wf := wfs.Indexs[0]
+	tests := []struct {
+		body    string
+		headers http.Header
+		want    bool
+	}{
+		{"Powered by Demo-App v1.2", http.Header{"Server": {"demo/3"}}, true},
+		{"Powered by Demo-App v1.x", http.Header{"Server": {"demo/3"}}, false},
+		{"Powered by Demo-App v1.2", http.Header{"Server": {"demo/3 beta"}}, false},
+		{"Powered by Demo-App v1.2", http.Header{}, false},
+	}
+	for _, tc := range tests {
+		if got := wf.MatchKeyWordResponse(&Response{Body: []byte(tc.body), Header: tc.headers, StatusCode: 200}); got != tc.want {
+			t.Errorf("MatchKeyWord(%q, %v) = %v; want %v", tc.body, tc.headers, got, tc.want)
+		}
+		// 兼容旧的调用方式
+		if got := wf.MatchKeyWord([]byte(tc.body), HTTPHeadersToMap(tc.headers), 200); got != tc.want {
+			t.Errorf("MatchKeyWord(%q, %v) = %v; want %v", tc.body, tc.headers, got, tc.want)
+		}
+		if got := len(wfs.MatchIndex([]byte(tc.body), tc.headers, 200, nil)) == 1; got != tc.want {
+			t.Errorf("MatchIndex(%q, %v) = %v; want %v", tc.body, tc.headers, got, tc.want)
+		}
+	}
+}
+
+func TestParseWebFingerInvalidRegex(t *testing.T) {
+	_, err := ParseWebFinger(`[{"path": "/", "request_method": "get", "keyword_regex": ["("], "name": "bad"}]`)
+	if err == nil {
+		t.Error("ParseWebFinger with invalid keyword_regex returns nil error")
+	}
+	_, err = ParseWebFinger(`[{"path": "/", "request_method": "get", "headers_regex": {"server": "["}, "name": "bad"}]`)
+	if err == nil {
+		t.Error("ParseWebFinger with invalid headers_regex returns nil error")
+	}
+}
+
+func TestMatchKeyWordGroups(t *testing.T) {
+	wfs, err := ParseWebFinger(`[{
+		"path": "/",
+		"request_method": "get",
+		"keyword": ["login"],
+		"any_of": [
+			{"keyword": ["seeyon"]},
+			{"headers": {"server": "seeyon"}},
+			{"all_of": [{"keyword": ["a8"]}, {"keyword_regex": ["v\\d+"]}]}
+		],
+		"none_of": [{"keyword": ["honeypot"]}],
+		"name": "seeyon-oa"
+	}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf := wfs.Indexs[0]
+	tests := []struct {
+		body    string
+		headers http.Header
+		want    bool
+	}{
+		{"Seeyon login", http.Header{}, true},
+		{"login", http.Header{"Server": {"seeyon-server"}}, true},
+		{"A8 v5 login", http.Header{}, true},
+		{"A8 login", http.Header{}, false},
+		{"Seeyon", http.Header{}, false},
+		{"Seeyon login honeypot", http.Header{}, false},
+	}
+	for _, tc := range tests {
+		if got := wf.MatchKeyWordResponse(&Response{Body: []byte(tc.body), Header: tc.headers, StatusCode: 200}); got != tc.want {
+			t.Errorf("MatchKeyWord(%q, %v) = %v; want %v", tc.body, tc.headers, got, tc.want)
+		}
+	}
+}
+
+func TestExtractVersion(t *testing.T) {
+	wfs, err := ParseWebFinger(`[{
+		"path": "/",
+		"request_method": "get",
+		"headers": {"server": "nginx"},
+		"version": [
+			{"from": "header", "name": "Server", "regex": "nginx/([\\d.]+)"},
+			{"from": "title", "regex": "nginx (?P<version>[\\d.]+)"}
+		],
+		"name": "nginx"
+	}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		resp *Response
+		want string
+	}{
+		{&Response{Header: http.Header{"Server": {"nginx/1.18.0"}}}, "nginx 1.18.0"},
+		{&Response{Header: http.Header{"Server": {"nginx"}}, Title: "Welcome to nginx 1.20.1"}, "nginx 1.20.1"},
+		{&Response{Header: http.Header{"Server": {"nginx"}}}, "nginx"},
+	}
+	for _, tc := range tests {
+		res := wfs.MatchIndexResponse(tc.resp)
+		if len(res) != 1 {
+			t.Errorf("len(MatchIndex(%v)) = %d; want 1", tc.resp.Header, len(res))
+			continue
+		}
+		if got := res[0].String(); got != tc.want {
+			t.Errorf("MatchIndex(%v) = %s; want %s", tc.resp.Header, got, tc.want)
+		}
+	}
+	_, err = ParseWebFinger(`[{"path": "/", "version": [{"from": "header", "regex": "(.*)"}], "name": "bad"}]`)
+	if err == nil {
+		t.Error("ParseWebFinger with header version extractor without name returns nil error")
+	}
+}
+
+func TestMatchEvidence(t *testing.T) {
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "request_method": "get", "status_code": 200, "keyword": ["Jenkins"], "headers_regex": {"X-Jenkins": "^2\\."}, "name": "jenkins"},
+		{"path": "/", "request_method": "get", "favicon_hash": ["81586312"], "name": "jenkins"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &Response{
+		URL:        "http://localhost/login",
+		StatusCode: 200,
+		Header:     http.Header{"X-Jenkins": {"2.426.1"}},
+		Body:       []byte("<title>Sign in [Jenkins]</title>"),
+		Favicons:   []string{"81586312"},
+	}
+	got := wfs.MatchIndexResponse(resp)
+	want := []WebFingerResult{{
+		Name:       "jenkins",
+		RootPath:   "/",
+		Confidence: 1 - (1-0.73)*(1-0.6),
+		Evidences: []Evidence{
+			{
+				URL:  "http://localhost/login",
+				Kind: MatchKindIndex,
+				Conditions: []Condition{
+					{ConditionStatusCode, "200"},
+					{ConditionHeaderRegex, "x-jenkins: 2."},
+					{ConditionKeyword, "Jenkins"},
+				},
+				Confidence: 1 - (1-0.1)*(1-0.5)*(1-0.4),
+			},
+			{
+				URL:        "http://localhost/login",
+				Kind:       MatchKindFavicon,
+				Conditions: []Condition{{ConditionFavicon, "81586312"}},
+				Confidence: 0.6,
+			},
+		},
+	}}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("got %#v; want %#v; diff: %#v", got, want, diff)
+	}
+}
+
+func TestCustomRequests(t *testing.T) {
+	wfs, err := ParseWebFinger(`[
+		{"path": "/nacos/", "request_method": "get", "keyword": ["nacos"], "name": "nacos"},
+		{"path": "/actuator/env", "request_method": "get", "keyword": ["activeProfiles"], "name": "spring-actuator"},
+		{"path": "/nacos/", "request_method": "GET", "status_code": 200, "name": "nacos-console"},
+		{"path": "/nacos/", "request_method": "post", "keyword": ["nacos"], "name": "nacos-post"},
+		{"path": "/api", "request_method": "get", "request_headers": {"X-Test": "1"}, "keyword": ["ok"], "name": "api-a"},
+		{"path": "/api", "request_method": "get", "request_headers": {"x-test": "1"}, "keyword": ["ok"], "name": "api-b"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, cr := range wfs.CustomRequests() {
+		var names []string
+		for _, wf := range cr.Fingers {
+			names = append(names, wf.Name)
+		}
+		got = append(got, names)
+	}
+	want := [][]string{
+		{"nacos", "nacos-console"},
+		{"spring-actuator"},
+		{"nacos-post"},
+		{"api-a", "api-b"},
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("got %v; want %v; diff: %v", got, want, diff)
+	}
+	cr := wfs.CustomRequests()[0]
+	res := wfs.MatchCustom(&cr, &Response{StatusCode: 200, Body: []byte("Nacos console")})
+	if len(res) != 2 {
+		t.Errorf("len(MatchCustom) = %d; want 2", len(res))
+	}
+}
+
+func TestResolvePriorityExcludes(t *testing.T) {
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "request_method": "get", "keyword": ["thinkphp"], "name": "ThinkPHP"},
+		{"path": "/", "request_method": "get", "keyword": ["nginx"], "name": "nginx", "priority": 1},
+		{"path": "/", "request_method": "get", "keyword": ["oa-login"], "name": "Some OA", "priority": 3,
+		 "implies": ["PHP"], "excludes": ["ThinkPHP"]},
+		{"path": "/", "request_method": "get", "keyword": ["x-powered-by: php"], "name": "PHP", "priority": 2, "excludes": ["Some OA"]},
+		{"path": "/", "request_method": "get", "keyword": ["aaa"], "name": "A", "excludes": ["B"]},
+		{"path": "/", "request_method": "get", "keyword": ["bbb"], "name": "B", "excludes": ["A"]},
+		{"path": "/", "request_method": "get", "keyword": ["wp-content"], "name": "WordPress", "implies": ["MySQL"], "excludes": ["Hugo"]},
+		{"path": "/", "request_method": "get", "keyword": ["hugo"], "name": "Hugo", "priority": 5, "implies": ["Go"], "excludes": ["WordPress"]},
+		{"path": "/", "request_method": "get", "keyword": ["golang"], "name": "Go", "implies": ["Linux"]},
+		{"path": "/", "request_method": "get", "keyword": ["stale"], "name": "Stale", "implies": ["Orphan"]},
+		{"path": "/", "request_method": "get", "keyword": ["orphan"], "name": "Orphan", "priority": 6, "implies": ["Ghost"], "excludes": ["Stale"]},
+		{"path": "/", "request_method": "get", "keyword": ["ghost"], "name": "Ghost", "priority": 7, "excludes": ["Orphan"]}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"thinkphp nginx", []string{"nginx", "ThinkPHP"}},
+		// 高优先度的 Some OA 排除 ThinkPHP，隐含的 PHP 使用规则中的优先度，但不能反过来排除 Some OA
+		{"thinkphp nginx oa-login", []string{"Some OA", "PHP", "nginx"}},
+		// 优先度相同时先出现的指纹生效
+		{"bbb aaa", []string{"A"}},
+		// 被排除的 WordPress 不再推导 MySQL，Hugo 推导的 Go 和 Linux 保留
+		{"wp-content hugo", []string{"Hugo", "Go", "Linux"}},
+		// 隐含的 Orphan 被自己推导的 Ghost 排除，不再排除 Stale，Ghost 的来源不存在后也被删除
+		{"stale", []string{"Stale"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range wfs.Resolve(wfs.MatchIndexResponse(&Response{StatusCode: 200, Body: []byte(tt.body)})) {
+			got = append(got, r.Name)
+		}
+		if diff := deep.Equal(got, tt.want); diff != nil {
+			t.Errorf("Resolve(%q) = %v; want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "request_method": "get", "keyword": ["generic"], "name": "keyword-only"},
+		{"path": "/", "request_method": "get", "favicon_hash": ["123"], "name": "favicon-only"},
+		{"path": "/admin", "request_method": "get", "status_code": 200, "keyword": ["generic"], "name": "custom"},
+		{"path": "/", "request_method": "get", "keyword": ["generic"], "name": "override", "confidence": 0.95, "implies": ["implied"]}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &Response{URL: "http://localhost/", StatusCode: 200, Body: []byte("generic"), Favicons: []string{"123"}}
+	results := wfs.MatchIndexResponse(resp)
+	cr := wfs.CustomRequests()[0]
+	results = append(results, wfs.MatchCustom(&cr, resp)...)
+	// 跳转链中的另一个页面再次命中，置信度累加
+	results = append(results, wfs.MatchIndexResponse(&Response{URL: "http://localhost/login", StatusCode: 200, Body: []byte("generic")})...)
+	got := make(map[string]float64)
+	for _, r := range wfs.Resolve(results) {
+		got[r.Name] = r.Confidence
+	}
+	want := map[string]float64{
+		"keyword-only": 1 - 0.6*0.6,
+		"favicon-only": 0.6,
+		"custom":       1 - 0.7*0.9*0.6,
+		"override":     1 - 0.05*0.05,
+		"implied":      (1 - 0.05*0.05) * 0.8,
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Error(diff)
+	}
+	if got := FilterConfidence(wfs.Resolve(results), 0.62); len(got) != 4 {
+		t.Errorf("len(FilterConfidence(0.62)) = %d; want 4", len(got))
+	}
+	if _, err := ParseWebFinger(`[{"path": "/", "request_method": "get", "keyword": ["a"], "name": "bad", "confidence": 2}]`); err == nil {
+		t.Error("ParseWebFinger with confidence 2: want error")
+	}
+}
+
+func TestMatchCookies(t *testing.T) {
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "request_method": "get", "cookies": {"JSESSIONID": "*"}, "name": "java"},
+		{"path": "/", "request_method": "get", "cookies": {"rememberMe": "deleteMe"}, "name": "shiro"},
+		{"path": "/", "request_method": "get", "cookies_regex": {"PHPSESSID": "^[a-z0-9]{26}$"}, "name": "php",
+		 "version": [{"from": "cookie", "name": "app_version", "regex": "^v([\\d.]+)"}]}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		cookies []string
+		want    []string
+	}{
+		{[]string{"jsessionid=ABC; Path=/; HttpOnly"}, []string{"java"}},
+		// 值需要完全相等，不能是子串
+		{[]string{"rememberMe=deleteMe; Path=/", "X-Other=JSESSIONID"}, []string{"shiro"}},
+		{[]string{"rememberMe=deleteMeNot"}, nil},
+		{[]string{"a=1", "PHPSESSID=abcdefghijklmnopqrstuvwxyz", "app_version=v8.1.2"}, []string{"php 8.1.2"}},
+		{[]string{"PHPSESSID=short"}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range wfs.MatchIndexResponse(&Response{StatusCode: 200, Header: http.Header{"Set-Cookie": tt.cookies}}) {
+			got = append(got, r.String())
+		}
+		if diff := deep.Equal(got, tt.want); diff != nil {
+			t.Errorf("MatchIndex(%v) = %v; want %v", tt.cookies, got, tt.want)
+		}
+	}
+}
+
+func TestMatchSelectors(t *testing.T) {
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "request_method": "get", "selectors": [{"selector": "meta[name=generator i]", "attr": "content", "regex": "^WordPress"}], "name": "wordpress"},
+		{"path": "/", "request_method": "get", "selectors": [{"selector": "#app[data-v-app]"}], "name": "vue"},
+		{"path": "/", "request_method": "get", "selectors": [{"selector": "div.login-box h2", "value": "seeyon"}], "name": "seeyon"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{`<meta name="Generator" content="WordPress 6.4.2">`, []string{"wordpress"}},
+		// 注释和脚本中的文本不会被选择器匹配
+		{`<!-- <meta name="generator" content="WordPress"> --><script>var s = '<div id="app" data-v-app>'</script>`, nil},
+		{`<div id="app" data-v-app=""></div><div class="login-box"><h2> Seeyon OA </h2></div>`, []string{"vue", "seeyon"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range wfs.MatchIndexResponse(&Response{StatusCode: 200, Body: []byte(tt.body)}) {
+			got = append(got, r.Name)
+		}
+		if diff := deep.Equal(got, tt.want); diff != nil {
+			t.Errorf("MatchIndex(%q) = %v; want %v", tt.body, got, tt.want)
+		}
+	}
+	res := wfs.MatchIndexResponse(&Response{StatusCode: 200, Body: []byte(`<meta name="generator" content="WordPress 6.4.2">`)})
+	want := []Condition{{ConditionSelector, "meta[name=generator i]@content: WordPress"}}
+	if diff := deep.Equal(res[0].Evidences[0].Conditions, want); diff != nil {
+		t.Error(diff)
+	}
+	if _, err := ParseWebFinger(`[{"path": "/", "request_method": "get", "selectors": [{"selector": "div["}], "name": "bad"}]`); err == nil {
+		t.Error("ParseWebFinger with invalid selector: want error")
+	}
+}
+
+func TestMatchJSON(t *testing.T) {
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "request_method": "get", "json": ["version.number", "tagline ~= \"You Know, for Search\""], "name": "elasticsearch",
+		 "version": [{"from": "json", "name": "version.number"}]},
+		{"path": "/", "request_method": "get", "json": ["status == \"UP\"", "components.db.status != 'DOWN'"], "name": "spring-actuator"},
+		{"path": "/", "request_method": "get", "json": ["$.data[1].name == nacos"], "name": "nacos",
+		 "version": [{"from": "json", "name": "data[1].version", "regex": "^(\\d+\\.\\d+)"}]},
+		{"path": "/", "request_method": "get", "json": ["cluster_name"], "name": "cluster",
+		 "version": [{"from": "json", "name": "version"}]}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{`{"name": "node-1", "version": {"number": "7.10.2"}, "tagline": "You Know, for Search"}`, []string{"elasticsearch 7.10.2"}},
+		{`{"status": "UP", "components": {"db": {"status": "UP"}}}`, []string{"spring-actuator"}},
+		{`{"status": "UP", "components": {"db": {"status": "DOWN"}}}`, nil},
+		{`{"data": [{"name": "x"}, {"name": "nacos", "version": 2.1}]}`, []string{"nacos 2.1"}},
+		// 不是 json 时不命中
+		{`<html>{"status": "UP"}</html>`, nil},
+		// null、对象和数组不作为版本号
+		{`{"cluster_name": "c", "version": null}`, []string{"cluster"}},
+		{`{"cluster_name": "c", "version": {"number": "7.10.2"}}`, []string{"cluster"}},
+		{`{"cluster_name": "c", "version": ["1"]}`, []string{"cluster"}},
+		{`{"cluster_name": "c", "version": 8}`, []string{"cluster 8"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range wfs.MatchIndexResponse(&Response{StatusCode: 200, Body: []byte(tt.body)}) {
+			got = append(got, r.String())
+		}
+		if diff := deep.Equal(got, tt.want); diff != nil {
+			t.Errorf("MatchIndex(%s) = %v; want %v", tt.body, got, tt.want)
+		}
+	}
+	for _, content := range []string{
+		`[{"path": "/", "request_method": "get", "json": ["a..b"], "name": "bad"}]`,
+		`[{"path": "/", "request_method": "get", "json": ["a ~= \"(\""], "name": "bad"}]`,
+		`[{"path": "/", "request_method": "get", "keyword": ["a"], "name": "bad", "version": [{"from": "json", "name": "a == 1"}]}]`,
+	} {
+		if _, err := ParseWebFinger(content); err == nil {
+			t.Errorf("ParseWebFinger(%s): want error", content)
+		}
+	}
+}
+
+// selfSignedCert 生成用于测试的自签名证书
+func selfSignedCert(t *testing.T) x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(0x1a2b3c),
+		Subject:      pkix.Name{CommonName: "FGT60E", Organization: []string{"Fortinet"}},
+		Issuer:       pkix.Name{CommonName: "support"},
+		DNSNames:     []string{"fortigate.local"},
+		IPAddresses:  []net.IP{net.ParseIP("192.168.1.99")},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *cert
+}
+
+func TestMatchCert(t *testing.T) {
+	cert := selfSignedCert(t)
+	sum := sha256.Sum256(cert.Raw)
+	tests := []struct {
+		rule string
+		want bool
+	}{
+		{`{"organization": "fortinet", "subject": "CN=FGT60E"}`, true},
+		{`{"san": "192.168.1.99"}`, true},
+		{`{"text": "fortigate.local"}`, true},
+		{`{"serial": "1A:2B:3C"}`, true},
+		{`{"serial": "1715004"}`, true},
+		{`{"fingerprint": "` + hex.EncodeToString(sum[:]) + `"}`, true},
+		{`{"organization": "fortinet", "issuer": "sangfor"}`, false},
+		{`{"serial": "1a2b3d"}`, false},
+	}
+	for _, tt := range tests {
+		wfs, err := ParseWebFinger(`[{"path": "/", "request_method": "get", "cert": ` + tt.rule + `, "name": "fortigate"}]`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 响应体为空时依然可以通过证书识别
+		got := len(wfs.MatchIndexResponse(&Response{StatusCode: 200, Certs: []x509.Certificate{cert}})) == 1
+		if got != tt.want {
+			t.Errorf("cert %s matched = %v; want %v", tt.rule, got, tt.want)
+		}
+		if len(wfs.MatchIndexResponse(&Response{StatusCode: 200})) != 0 {
+			t.Errorf("cert %s matched response without certificate", tt.rule)
+		}
+	}
+}
+
+func TestMatchTitle(t *testing.T) {
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "request_method": "get", "title": ["jenkins"], "name": "jenkins"},
+		{"path": "/", "request_method": "get", "title_equals": "Login", "name": "login"},
+		{"path": "/", "request_method": "get", "title_regex": ["^Apache Tomcat/([\\d.]+)$"], "name": "tomcat",
+		 "version": [{"from": "title", "regex": "([\\d.]+)"}]}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		resp *Response
+		want []string
+	}{
+		{&Response{Title: "Sign in [Jenkins]"}, []string{"jenkins"}},
+		// 只匹配提取出的标题，正文中的关键词不算
+		{&Response{Body: []byte("<title>Dashboard</title><p>Jenkins</p>"), Title: "Dashboard"}, nil},
+		{&Response{Title: " login "}, []string{"login"}},
+		{&Response{Title: "Login - Admin"}, nil},
+		{&Response{Title: "Apache Tomcat/9.0.65"}, []string{"tomcat 9.0.65"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range wfs.MatchIndexResponse(tt.resp) {
+			got = append(got, r.String())
+		}
+		if diff := deep.Equal(got, tt.want); diff != nil {
+			t.Errorf("MatchIndex(title %q) = %v; want %v", tt.resp.Title, got, tt.want)
+		}
+	}
+}
+
+func TestMatchStatusCodes(t *testing.T) {
+	tests := []struct {
+		statusCode string
+		matched    []int
+		unmatched  []int
+	}{
+		{`0`, []int{200, 404}, nil},
+		{`200`, []int{200}, []int{302}},
+		{`"302"`, []int{302}, []int{200}},
+		{`[200, "301-302", "4xx"]`, []int{200, 301, 302, 401, 499}, []int{303, 500}},
+		{`"!404"`, []int{200, 500}, []int{404}},
+		{`["4xx", "!404"]`, []int{401, 403}, []int{404, 200}},
+		{`["50x"]`, []int{500, 503}, []int{510}},
+	}
+	for _, tt := range tests {
+		wfs, err := ParseWebFinger(`[{"path": "/", "request_method": "get", "keyword": ["a"], "status_code": ` + tt.statusCode + `, "name": "a"}]`)
+		if err != nil {
+			t.Fatalf("status_code %s: %v", tt.statusCode, err)
+		}
+		for _, code := range tt.matched {
+			if len(wfs.MatchIndexResponse(&Response{StatusCode: code, Body: []byte("a")})) != 1 {
+				t.Errorf("status_code %s: %d should match", tt.statusCode, code)
+			}
+		}
+		for _, code := range tt.unmatched {
+			if len(wfs.MatchIndexResponse(&Response{StatusCode: code, Body: []byte("a")})) != 0 {
+				t.Errorf("status_code %s: %d should not match", tt.statusCode, code)
+			}
+		}
+	}
+	for _, statusCode := range []string{`"abc"`, `"302-301"`, `"6xy"`, `200.5`, `[true]`} {
+		if _, err := ParseWebFinger(`[{"path": "/", "request_method": "get", "status_code": ` + statusCode + `, "name": "bad"}]`); err == nil {
+			t.Errorf("status_code %s: want error", statusCode)
+		}
+	}
+}
+
+func TestMatchBodyHashContentLength(t *testing.T) {
+	body := []byte("<html>router</html>")
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "request_method": "get", "body_hash": ["` + strings.ToUpper(utils.MD5Hex(body)) + `"], "name": "md5"},
+		{"path": "/", "request_method": "get", "body_hash": ["` + utils.MMH3Hash(body) + `"], "name": "mmh3"},
+		{"path": "/", "request_method": "get", "content_length": 19, "name": "exact"},
+		{"path": "/", "request_method": "get", "content_length": ["10-20", "4096-"], "name": "range"},
+		{"path": "/", "request_method": "get", "content_length": "-0", "name": "empty"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		resp *Response
+		want []string
+	}{
+		{&Response{StatusCode: 200, Body: body}, []string{"md5", "mmh3", "exact", "range"}},
+		{&Response{StatusCode: 200, Body: body, BodyHashes: []string{utils.MD5Hex(body)}}, []string{"md5", "exact", "range"}},
+		{&Response{StatusCode: 200, Body: make([]byte, 5000)}, []string{"range"}},
+		{&Response{StatusCode: 200}, []string{"empty"}},
+	}
+	for i, tt := range tests {
+		var got []string
+		for _, result := range wfs.MatchIndexResponse(tt.resp) {
+			got = append(got, result.Name)
+		}
+		if diff := deep.Equal(got, tt.want); diff != nil {
+			t.Errorf("#%d: %v", i, diff)
+		}
+	}
+	for _, contentLength := range []string{`"abc"`, `"200-100"`, `""`, `1.5`} {
+		if _, err := ParseWebFinger(`[{"path": "/", "request_method": "get", "content_length": ` + contentLength + `, "name": "bad"}]`); err == nil {
+			t.Errorf("content_length %s: want error", contentLength)
+		}
+	}
+}
+
+func TestMatchHeadersBuiltInCode(t *testing.T) {
+	tests := []struct {
+		rule MatchRule
+		want bool
+	}{
+		{MatchRule{Headers: map[string]string{"Server": "Nginx"}}, true},
+		{MatchRule{Headers: map[string]string{"server": "NGINX/1"}}, true},
+		{MatchRule{Headers: map[string]string{"Server": "Nginx"}, CaseSensitive: true}, false},
+		{MatchRule{Headers: map[string]string{"server": "nginx"}, CaseSensitive: true}, true},
+	}
+	for i, tt := range tests {
+		wf := WebFinger{Name: "nginx", MatchRules: tt.rule}
+		resp := &Response{StatusCode: 200, Header: http.Header{"Server": {"nginx/1.24.0"}}}
+		if got := wf.MatchKeyWordResponse(resp); got != tt.want {
+			t.Errorf("#%d: MatchKeyWord = %v; want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestMatchScopeCaseSensitive(t *testing.T) {
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "request_method": "get", "keyword": ["ThinkPHP"], "case_sensitive": true, "name": "thinkphp"},
+		{"path": "/", "request_method": "get", "headers": {"X-Powered-By": "ASP.NET"}, "case_sensitive": true, "name": "aspnet"},
+		{"path": "/", "request_method": "get", "keyword_regex": ["^HTTP/1\\.1 401 Unauthorized"], "scope": "raw", "name": "basic-auth"},
+		{"path": "/", "request_method": "get", "keyword": ["layui.config"], "scope": "script", "name": "layui"},
+		{"path": "/", "request_method": "get", "keyword": ["powered by dedecms"], "scope": "comment", "name": "dedecms"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		resp *Response
+		want []string
+	}{
+		{&Response{StatusCode: 200, Body: []byte("<p>ThinkPHP</p>")}, []string{"thinkphp"}},
+		{&Response{StatusCode: 200, Body: []byte("<p>thinkphp</p>")}, nil},
+		{&Response{StatusCode: 200, Header: http.Header{"X-Powered-By": {"ASP.NET"}}}, []string{"aspnet"}},
+		{&Response{StatusCode: 200, Header: http.Header{"X-Powered-By": {"asp.net"}}}, nil},
+		{&Response{StatusCode: 401, Header: http.Header{"Www-Authenticate": {`Basic realm="x"`}}}, []string{"basic-auth"}},
+		{&Response{StatusCode: 200, Body: []byte(`<p>layui.config</p><script>layui.config({base: "/"})</script>`)}, []string{"layui"}},
+		{&Response{StatusCode: 200, Body: []byte(`<p>layui.config</p><script src="/layui.js"></script>`)}, nil},
+		{&Response{StatusCode: 200, Body: []byte(`<!-- Powered by DedeCMS --><p>hi</p>`)}, []string{"dedecms"}},
+		{&Response{StatusCode: 200, Body: []byte(`<p>Powered by DedeCMS</p>`)}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range wfs.MatchIndexResponse(tt.resp) {
+			got = append(got, r.Name)
+		}
+		if diff := deep.Equal(got, tt.want); diff != nil {
+			t.Errorf("MatchIndex(%d %v %s) = %v; want %v", tt.resp.StatusCode, tt.resp.Header, tt.resp.Body, got, tt.want)
+		}
+	}
+}
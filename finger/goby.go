@@ -0,0 +1,100 @@
+package finger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GobyMatch Goby 规则中的一个条件
+type GobyMatch struct {
+	Match   string `json:"match"`
+	Content string `json:"content"`
+}
+
+// GobyFinger 社区导出的 Goby 指纹，rule 中外层为或关系，内层为与关系
+type GobyFinger struct {
+	Name     string        `json:"name"`
+	Product  string        `json:"product"`
+	Company  string        `json:"company"`
+	Category string        `json:"category"`
+	Rule     [][]GobyMatch `json:"rule"`
+	Rules    [][]GobyMatch `json:"rules"` // 部分导出文件使用 rules
+}
+
+// toMatchRule 转换单个条件，不支持的条件（如 protocol_contains）返回 false
+func (gm *GobyMatch) toMatchRule() (MatchRule, bool) {
+	switch strings.ToLower(gm.Match) {
+	case "body_contains":
+		return MatchRule{Keyword: []string{gm.Content}}, true
+	case "title_contains":
+		return MatchRule{Title: []string{gm.Content}}, true
+	case "header_contains", "banner_contains":
+		return MatchRule{Keyword: []string{gm.Content}, Scope: ScopeHeader}, true
+	case "cert_contains":
+		return MatchRule{Cert: &CertRule{Text: gm.Content}}, true
+	case "server", "server_contains":
+		return MatchRule{Headers: map[string]string{"server": strings.ToLower(gm.Content)}}, true
+	}
+	return MatchRule{}, false
+}
+
+// toWebFinger 转换为首页指纹，包含不支持条件的与条件组会被跳过，没有可用条件组时返回 nil
+// warnings 为被跳过的条件组或整条指纹及原因
+func (gf *GobyFinger) toWebFinger() (wf *WebFinger, warnings []string) {
+	name := gf.Name
+	if name == "" {
+		name = gf.Product
+	}
+	var anyOf []MatchRule
+	for gi, group := range append(gf.Rule, gf.Rules...) {
+		var allOf []MatchRule
+		for i := range group {
+			mr, ok := group[i].toMatchRule()
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("跳过 Goby 指纹 %s 的第 %d 个条件组: 不支持的条件 %s", name, gi+1, group[i].Match))
+				allOf = nil
+				break
+			}
+			allOf = append(allOf, mr)
+		}
+		if len(allOf) != 0 {
+			anyOf = append(anyOf, MatchRule{AllOf: allOf})
+		}
+	}
+	if len(anyOf) == 0 {
+		return nil, append(warnings, fmt.Sprintf("跳过 Goby 指纹 %s: 没有可用的条件组", name))
+	}
+	return &WebFinger{
+		Metadata:   Metadata{Vendor: gf.Company, Product: gf.Product, Category: gf.Category},
+		Name:       name,
+		Request:    RequestInfo{Path: "/", RequestMethod: "get"},
+		MatchRules: MatchRule{AnyOf: anyOf},
+		RootPath:   "/",
+	}, warnings
+}
+
+func isGoby(content string) bool {
+	first := jsonFirstElement(content)
+	_, ok := first["rule"]
+	_, ok2 := first["rules"]
+	return ok || ok2
+}
+
+// ParseGoby 解析 Goby 格式的指纹列表，被跳过的条件组和指纹记录在 Warnings 中
+func ParseGoby(content string) (*WebFingerSystem, error) {
+	var gfList []GobyFinger
+	if err := json.Unmarshal([]byte(content), &gfList); err != nil {
+		return nil, err
+	}
+	wfs := new(WebFingerSystem)
+	for i := range gfList {
+		wf, warnings := gfList[i].toWebFinger()
+		wfs.Warnings = append(wfs.Warnings, warnings...)
+		if wf != nil {
+			wfs.Indexs = append(wfs.Indexs, *wf)
+		}
+	}
+	wfs.Build()
+	return wfs, nil
+}
@@ -0,0 +1,129 @@
+package finger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 指纹格式名称
+const (
+	FormatAuto           = "auto" // 自动识别指纹格式
+	FormatEHole          = "ehole"
+	FormatGoby           = "goby"
+	FormatWappalyzer     = "wappalyzer"
+	FormatNuclei         = "nuclei"
+	FormatFingerprintHub = "fingerprinthub"
+)
+
+// Importer 指纹格式导入器，所有格式最终都转换为 WebFingerSystem
+type Importer struct {
+	Name   string                                         // 格式名称
+	Detect func(content string) bool                      // 判断内容是否为该格式，用于自动识别
+	Parse  func(content string) (*WebFingerSystem, error) // 解析为指纹系统
+}
+
+// importers 已注册的导入器，自动识别时按顺序判断，越宽松的格式越靠后
+var importers = []Importer{
+	{Name: FormatEHole, Detect: isEHole, Parse: ParseEHole},
+	{Name: FormatGoby, Detect: isGoby, Parse: ParseGoby},
+	{Name: FormatWappalyzer, Detect: isWappalyzer, Parse: ParseWappalyzer},
+	{Name: FormatNuclei, Detect: isNuclei, Parse: ParseNuclei},
+	{Name: FormatFingerprintHub, Detect: isFingerprintHub, Parse: ParseWebFinger},
+}
+
+// RegisterImporter 注册指纹格式导入器，同名的导入器会被替换，新的导入器在自动识别时优先判断
+func RegisterImporter(imp Importer) {
+	for i := range importers {
+		if importers[i].Name == imp.Name {
+			importers = append(importers[:i], importers[i+1:]...)
+			break
+		}
+	}
+	importers = append([]Importer{imp}, importers...)
+}
+
+// Formats 返回所有支持的指纹格式名称
+func Formats() []string {
+	var names []string
+	for _, imp := range importers {
+		names = append(names, imp.Name)
+	}
+	return names
+}
+
+// DetectFormat 自动识别指纹格式
+func DetectFormat(content string) (string, error) {
+	for _, imp := range importers {
+		if imp.Detect != nil && imp.Detect(content) {
+			return imp.Name, nil
+		}
+	}
+	return "", fmt.Errorf("无法识别的指纹格式，支持的格式：%s", strings.Join(Formats(), ", "))
+}
+
+// ParseWebFingerFormat 使用指定格式解析指纹，format 为空或 auto 时自动识别
+func ParseWebFingerFormat(format string, content string) (*WebFingerSystem, error) {
+	format = strings.ToLower(format)
+	if format == "" || format == FormatAuto {
+		var err error
+		if format, err = DetectFormat(content); err != nil {
+			return nil, err
+		}
+	}
+	for _, imp := range importers {
+		if imp.Name == format {
+			return imp.Parse(content)
+		}
+	}
+	return nil, fmt.Errorf("不支持的指纹格式 %s，支持的格式：%s", format, strings.Join(Formats(), ", "))
+}
+
+// jsonObject 把内容解析为 json 对象，失败时返回 nil
+func jsonObject(content string) map[string]json.RawMessage {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &obj); err != nil {
+		return nil
+	}
+	return obj
+}
+
+// jsonFirstElement 把内容解析为 json 对象列表，返回第一个元素，失败或为空时返回 nil
+func jsonFirstElement(content string) map[string]json.RawMessage {
+	var list []json.RawMessage
+	if err := json.Unmarshal([]byte(content), &list); err != nil || len(list) == 0 {
+		return nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(list[0], &obj); err != nil {
+		return nil
+	}
+	return obj
+}
+
+func isFingerprintHub(content string) bool {
+	first := jsonFirstElement(content)
+	_, ok := first["name"]
+	return ok
+}
+
+func isWappalyzer(content string) bool {
+	obj := jsonObject(content)
+	if obj == nil {
+		return false
+	}
+	if _, ok := obj["technologies"]; ok {
+		return true
+	}
+	if _, ok := obj["apps"]; ok {
+		return true
+	}
+	// 按字母拆分的文件，每个值都是一个技术定义
+	for _, v := range obj {
+		var tech map[string]json.RawMessage
+		if err := json.Unmarshal(v, &tech); err != nil {
+			return false
+		}
+	}
+	return len(obj) > 0
+}
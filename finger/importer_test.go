@@ -0,0 +1,115 @@
+package finger
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+const (
+	testEHoleContent = `{"fingerprint": [
+		{"cms": "seeyon", "method": "keyword", "location": "body", "keyword": ["/seeyon/USER-DATA/IMAGES/LOGIN/login.gif"]},
+		{"cms": "shiro", "method": "keyword", "location": "header", "keyword": ["rememberMe=deleteMe"]},
+		{"cms": "Jenkins", "method": "regula", "location": "title", "keyword": ["Sign in \\[Jenkins\\]"]},
+		{"cms": "ruijie", "method": "keyword", "location": "title", "keyword": ["Ruijie"]},
+		{"cms": "jeecms", "method": "faviconhash", "location": "body", "keyword": ["-1125396806"]}
+	]}`
+	testGobyContent = `[
+		{"name": "Apache-Shiro", "rule": [[{"match": "header_contains", "content": "rememberMe=deleteMe"}], [{"match": "cert_contains", "content": "shiro"}]]},
+		{"name": "", "product": "Nginx", "rules": [[{"match": "server", "content": "nginx"}, {"match": "title_contains", "content": "Welcome"}]]},
+		{"name": "Fortinet", "rule": [[{"match": "cert_contains", "content": "fortinet"}]]},
+		{"name": "protocol-only", "rule": [[{"match": "protocol_contains", "content": "x"}]]},
+		{"name": "Mixed", "rule": [[{"match": "protocol_contains", "content": "x"}], [{"match": "body_contains", "content": "mixed"}]]}
+	]`
+	testHubContent = `[{"path": "/", "request_method": "get", "keyword": ["a"], "name": "a"}]`
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{testEHoleContent, "ehole"},
+		{testGobyContent, "goby"},
+		{testHubContent, "fingerprinthub"},
+		{`{"technologies": {"Nginx": {"headers": {"Server": "nginx"}}}}`, "wappalyzer"},
+		{`{"Nginx": {"headers": {"Server": "nginx"}}}`, "wappalyzer"},
+		{`"nothing"`, ""},
+	}
+	for _, tc := range tests {
+		got, _ := DetectFormat(tc.content)
+		if got != tc.want {
+			t.Errorf("DetectFormat(%.30s) = %s; want %s", tc.content, got, tc.want)
+		}
+	}
+	if _, err := ParseWebFingerFormat("unknown", testHubContent); err == nil {
+		t.Error("ParseWebFingerFormat(unknown) returns nil error")
+	}
+}
+
+func matchNames(wfs *WebFingerSystem, resp *Response) []string {
+	var names []string
+	for _, r := range wfs.Resolve(wfs.MatchIndexResponse(resp)) {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestParseEHole(t *testing.T) {
+	wfs, err := ParseWebFingerFormat(FormatAuto, testEHoleContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		resp *Response
+		want []string
+	}{
+		{&Response{Body: []byte(`<img src="/seeyon/USER-DATA/IMAGES/LOGIN/login.gif">`)}, []string{"seeyon"}},
+		{&Response{Header: http.Header{"Set-Cookie": {"rememberMe=deleteMe; Path=/"}}}, []string{"shiro"}},
+		{&Response{Body: []byte("rememberMe=deleteMe")}, nil},
+		{&Response{Body: []byte("<title>Sign in [Jenkins]</title>"), Title: "Sign in [Jenkins]"}, []string{"Jenkins"}},
+		{&Response{Body: []byte("<title>Login</title><p>Ruijie</p>"), Title: "Login"}, nil},
+		{&Response{Favicons: []string{"-1125396806"}}, []string{"jeecms"}},
+	}
+	for _, tc := range tests {
+		if diff := deep.Equal(matchNames(wfs, tc.resp), tc.want); diff != nil {
+			t.Errorf("MatchIndex(%s) diff: %v", tc.resp.Body, diff)
+		}
+	}
+}
+
+func TestParseGoby(t *testing.T) {
+	wfs, err := ParseWebFingerFormat("goby", testGobyContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wfs.Count() != 4 {
+		t.Errorf("wfs.Count() = %d; want 4", wfs.Count())
+	}
+	wantWarnings := []string{
+		"跳过 Goby 指纹 protocol-only 的第 1 个条件组: 不支持的条件 protocol_contains",
+		"跳过 Goby 指纹 protocol-only: 没有可用的条件组",
+		"跳过 Goby 指纹 Mixed 的第 1 个条件组: 不支持的条件 protocol_contains",
+	}
+	if diff := deep.Equal(wfs.Warnings, wantWarnings); diff != nil {
+		t.Errorf("Warnings = %v; diff: %v", wfs.Warnings, diff)
+	}
+	tests := []struct {
+		resp *Response
+		want []string
+	}{
+		{&Response{Header: http.Header{"Set-Cookie": {"rememberMe=deleteMe"}}}, []string{"Apache-Shiro"}},
+		{&Response{Body: []byte("mixed")}, []string{"Mixed"}},
+		{&Response{Header: http.Header{"Server": {"nginx/1.20"}}, Body: []byte("<title>Welcome to nginx</title>"), Title: "Welcome to nginx"}, []string{"Nginx"}},
+		{&Response{Header: http.Header{"Server": {"nginx/1.20"}}}, nil},
+		{&Response{Certs: []x509.Certificate{{Subject: pkix.Name{CommonName: "FortiGate", Organization: []string{"Fortinet"}}}}}, []string{"Fortinet"}},
+	}
+	for _, tc := range tests {
+		if diff := deep.Equal(matchNames(wfs, tc.resp), tc.want); diff != nil {
+			t.Errorf("MatchIndex(%v) diff: %v", tc.resp.Header, diff)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package finger
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// json 表达式中的比较运算符
+const (
+	JSONOpExists   = ""   // 路径存在且值不为 null
+	JSONOpEqual    = "==" // 值的文本完全相等
+	JSONOpNotEqual = "!=" // 路径存在且值的文本不相等
+	JSONOpRegex    = "~=" // 值的文本匹配正则，默认不区分大小写
+)
+
+// JSONExpr json 路径表达式，如 version.number、status == "UP"、nodes[0].version ~= "^7\."
+// 路径使用 . 分隔对象的键，数组下标可以写成 [0] 或者 .0，可以带 $. 前缀
+type JSONExpr struct {
+	Path  []string       // 路径的每一段
+	Op    string         // 运算符，见 JSONOpExists 等
+	Value string         // 比较的值，字符串需要使用引号，数字、true、false、null 可以直接写
+	Regex *regexp.Regexp // Op 为 ~= 时编译后的正则
+	expr  string
+}
+
+// ParseJSONExpr 解析 json 路径表达式
+func ParseJSONExpr(expr string) (je JSONExpr, err error) {
+	je.expr = strings.TrimSpace(expr)
+	path := je.expr
+	// 使用最先出现的运算符，路径中不能包含运算符
+	opIndex := -1
+	for _, op := range []string{JSONOpEqual, JSONOpNotEqual, JSONOpRegex} {
+		if i := strings.Index(je.expr, op); i >= 0 && (opIndex < 0 || i < opIndex) {
+			opIndex, je.Op = i, op
+		}
+	}
+	if opIndex >= 0 {
+		path = strings.TrimSpace(je.expr[:opIndex])
+		if je.Value, err = parseJSONExprValue(strings.TrimSpace(je.expr[opIndex+len(je.Op):])); err != nil {
+			return je, fmt.Errorf("json 表达式 %s 的值不合法: %w", expr, err)
+		}
+	}
+	if je.Path, err = parseJSONPath(path); err != nil {
+		return je, fmt.Errorf("json 表达式 %s 的路径不合法: %w", expr, err)
+	}
+	if je.Op == JSONOpRegex {
+		if je.Regex, err = compileRegex(je.Value); err != nil {
+			return je, fmt.Errorf("json 表达式 %s 的正则不合法: %w", expr, err)
+		}
+	}
+	return je, nil
+}
+
+// parseJSONExprValue 去掉值两边的引号，双引号按 json 字符串解析转义，单引号不处理转义
+func parseJSONExprValue(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		var v string
+		err := json.Unmarshal([]byte(s), &v)
+		return v, err
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1], nil
+	}
+	return s, nil
+}
+
+// parseJSONPath 把 a.b[0].c 拆分为 a、b、0、c
+func parseJSONPath(path string) ([]string, error) {
+	path = strings.TrimPrefix(path, "$")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil, fmt.Errorf("路径为空")
+	}
+	parts := strings.Split(path, ".")
+	for _, p := range parts {
+		if p == "" {
+			return nil, fmt.Errorf("路径 %s 中有空的段", path)
+		}
+	}
+	return parts, nil
+}
+
+// lookupJSON 按路径查找值，数组使用数字下标
+func lookupJSON(v any, path []string) (any, bool) {
+	for _, p := range path {
+		switch node := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = node[p]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// jsonText 返回值用于比较和提取的文本，字符串不带引号，对象和数组为紧凑的 json
+func jsonText(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case nil:
+		return "null"
+	}
+	d, _ := json.Marshal(v)
+	return string(d)
+}
+
+// Lookup 返回路径对应的字符串或数字的文本，路径不存在或值为 null、布尔值、对象、数组时返回 false
+func (je *JSONExpr) Lookup(doc any) (string, bool) {
+	v, _ := lookupJSON(doc, je.Path)
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case json.Number:
+		return t.String(), true
+	}
+	return "", false
+}
+
+// match 判断表达式是否成立，成立时返回路径和实际的值，如 status: UP
+func (je *JSONExpr) match(doc any) (string, bool) {
+	v, ok := lookupJSON(doc, je.Path)
+	if !ok {
+		return "", false
+	}
+	text := jsonText(v)
+	switch je.Op {
+	case JSONOpExists:
+		ok = v != nil
+	case JSONOpEqual:
+		ok = text == je.Value
+	case JSONOpNotEqual:
+		ok = text != je.Value
+	case JSONOpRegex:
+		ok = je.Regex.MatchString(text)
+	}
+	if !ok {
+		return "", false
+	}
+	return strings.Join(je.Path, ".") + ": " + text, true
+}
+
+func (je JSONExpr) String() string {
+	return je.expr
+}
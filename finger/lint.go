@@ -0,0 +1,216 @@
+package finger
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// 问题级别
+const (
+	LintError   = "error"   // 规则无效或会匹配所有站点
+	LintWarning = "warning" // 规则可以使用，但行为可能和预期不一致
+)
+
+// LintIssue 指纹规则中发现的问题
+type LintIssue struct {
+	Index   int    // 指纹在文件中的下标，从 0 开始，为 -1 时表示解析时跳过的规则
+	Name    string // 指纹名称
+	Level   string // 问题级别：error、warning
+	Message string
+}
+
+func (li LintIssue) String() string {
+	if li.Index < 0 {
+		return fmt.Sprintf("[%s] %s", li.Level, li.Message)
+	}
+	return fmt.Sprintf("[%s] #%d %s: %s", li.Level, li.Index, li.Name, li.Message)
+}
+
+// HasLintError 判断问题中是否有 error 级别的问题
+func HasLintError(issues []LintIssue) bool {
+	for _, issue := range issues {
+		if issue.Level == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// httpMethods 常见的 HTTP 方法
+var httpMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace, "PROPFIND",
+}
+
+// isEmpty 判断规则是否没有任何条件，即任何响应都能命中
+func (mr *MatchRule) isEmpty() bool {
+	if !mr.StatusCode.IsEmpty() || !mr.ContentLength.IsEmpty() || len(mr.BodyHash) != 0 ||
+		len(mr.Headers) != 0 || len(mr.HeadersRegex) != 0 || len(mr.Keyword) != 0 || len(mr.KeywordRegex) != 0 || len(mr.Title) != 0 ||
+		mr.TitleEquals != "" || len(mr.TitleRegex) != 0 || len(mr.Cookies) != 0 ||
+		len(mr.CookiesRegex) != 0 || len(mr.Selectors) != 0 ||
+		len(mr.JSON) != 0 || mr.Cert != nil || len(mr.NoneOf) != 0 {
+		return false
+	}
+	for i := range mr.AllOf {
+		if !mr.AllOf[i].isEmpty() {
+			return false
+		}
+	}
+	if len(mr.AnyOf) == 0 {
+		return true
+	}
+	for i := range mr.AnyOf {
+		if mr.AnyOf[i].isEmpty() {
+			return true
+		}
+	}
+	return false
+}
+
+// lintFinger 检查单个已编译的指纹
+func lintFinger(index int, wf *WebFinger) []LintIssue {
+	var issues []LintIssue
+	add := func(level string, format string, a ...any) {
+		issues = append(issues, LintIssue{index, wf.Name, level, fmt.Sprintf(format, a...)})
+	}
+	if strings.TrimSpace(wf.Name) == "" {
+		add(LintError, "指纹名称为空")
+	}
+	switch {
+	case wf.IsIndex():
+		if wf.MatchRules.isEmpty() {
+			add(LintError, "首页指纹没有任何匹配条件，会命中所有站点")
+		}
+	case wf.IsFavicon():
+		if !wf.MatchRules.isEmpty() {
+			add(LintWarning, "图标指纹只匹配 favicon_hash 和 favicon_phash，其余匹配条件会被忽略")
+		}
+		if wf.Request.Path != "/" || len(wf.Request.RequestHeader) != 0 || len(wf.Request.RequestData) != 0 ||
+			(wf.Request.RequestMethod != "" && wf.Request.RequestMethod != "get") {
+			add(LintWarning, "图标指纹不会发送自定义请求，请求配置会被忽略")
+		}
+	default:
+		// 既不是首页指纹也不是图标指纹的都是自定义请求指纹
+		if wf.MatchRules.isEmpty() {
+			add(LintError, "自定义请求指纹没有任何匹配条件，只要请求成功就会命中")
+		}
+		if wf.Request.Path == "/" && wf.Request.RequestMethod == "" && len(wf.Request.RequestHeader) == 0 && len(wf.Request.RequestData) == 0 {
+			add(LintWarning, "request_method 为空，会被当作自定义请求而不是首页指纹，首页指纹请使用 get")
+		}
+		method := strings.ToUpper(wf.Request.RequestMethod)
+		switch {
+		case method == "":
+		case !httpguts.ValidHeaderFieldName(method):
+			add(LintError, "request_method %q 不是合法的 HTTP 方法，请求总会失败，指纹不会被使用", wf.Request.RequestMethod)
+		case !slices.Contains(httpMethods, method):
+			add(LintWarning, "request_method %q 不是常见的 HTTP 方法，服务器可能会拒绝请求", wf.Request.RequestMethod)
+		}
+	}
+	return issues
+}
+
+// lintNames 检查仅大小写或首尾空白不同的指纹名称
+func lintNames(names []string) []LintIssue {
+	var issues []LintIssue
+	first := make(map[string]int)
+	for i, name := range names {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key == "" {
+			continue
+		}
+		j, ok := first[key]
+		if !ok {
+			first[key] = i
+			continue
+		}
+		if names[j] != name {
+			issues = append(issues, LintIssue{i, name, LintWarning, fmt.Sprintf("和第 %d 条指纹的名称 %q 冲突，只有大小写或空白不同", j, names[j])})
+		}
+	}
+	return issues
+}
+
+// Validate 检查 FingerprintHub 格式的指纹文件，返回所有发现的问题
+// 包括：无法解析的字段（base64、root_path、正则等）、没有匹配条件的规则、重复的规则、冲突的名称以及被分类逻辑忽略的配置
+// 只有文件本身不是合法的 json 列表时才会返回 error
+func Validate(content string) ([]LintIssue, error) {
+	var rawList []json.RawMessage
+	if err := json.Unmarshal([]byte(content), &rawList); err != nil {
+		return nil, err
+	}
+	var issues []LintIssue
+	var names []string
+	seen := make(map[string]int)
+	for i, raw := range rawList {
+		var wfr WebFingerRaw
+		if err := json.Unmarshal(raw, &wfr); err != nil {
+			issues = append(issues, LintIssue{i, "", LintError, err.Error()})
+			names = append(names, "")
+			continue
+		}
+		names = append(names, wfr.Name)
+		add := func(level string, format string, a ...any) {
+			issues = append(issues, LintIssue{i, wfr.Name, level, fmt.Sprintf(format, a...)})
+		}
+		// 重复的规则，重新序列化以忽略字段顺序和空白的差异
+		normalized, _ := json.Marshal(wfr)
+		if j, ok := seen[string(normalized)]; ok {
+			add(LintWarning, "和第 %d 条指纹完全相同", j)
+		} else {
+			seen[string(normalized)] = i
+		}
+		if _, err := base64.StdEncoding.DecodeString(wfr.RequestData); err != nil {
+			add(LintError, "request_data 不是合法的 base64: %v", err)
+			continue
+		}
+		if wfr.RootPath != "" {
+			if err := CheckRootPath(wfr.RootPath); err != nil {
+				add(LintError, "root_path %q 不合法: %v", wfr.RootPath, err)
+				continue
+			}
+		}
+		wf, err := wfr.toWebFinger()
+		if err != nil {
+			add(LintError, "%v", err)
+			continue
+		}
+		issues = append(issues, lintFinger(i, wf)...)
+	}
+	issues = append(issues, lintNames(names)...)
+	sortLintIssues(issues)
+	return issues, nil
+}
+
+// ValidateSystem 检查已经解析的指纹系统，用于 FingerprintHub 以外的格式
+// 下标按照首页、自定义请求、图标指纹的顺序计算，解析时跳过的规则作为下标为 -1 的 warning
+func ValidateSystem(wfs *WebFingerSystem) []LintIssue {
+	var issues []LintIssue
+	for _, warning := range wfs.Warnings {
+		issues = append(issues, LintIssue{-1, "", LintWarning, warning})
+	}
+	var names []string
+	index := 0
+	for _, fingers := range [][]WebFinger{wfs.Indexs, wfs.CustomReqs, wfs.Favicons} {
+		for i := range fingers {
+			issues = append(issues, lintFinger(index, &fingers[i])...)
+			names = append(names, fingers[i].Name)
+			index++
+		}
+	}
+	issues = append(issues, lintNames(names)...)
+	sortLintIssues(issues)
+	return issues
+}
+
+func sortLintIssues(issues []LintIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Index < issues[j].Index
+	})
+}
@@ -0,0 +1,58 @@
+package finger
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestValidate(t *testing.T) {
+	issues, err := Validate(`[
+		{"path": "/", "request_method": "get", "name": "empty"},
+		{"path": "/", "request_method": "get", "keyword": ["a"], "name": "Dup"},
+		{"path": "/", "request_method": "get", "keyword": ["a"], "name": "Dup"},
+		{"path": "/", "request_method": "get", "keyword": ["b"], "name": "dup "},
+		{"path": "/x", "request_data": "!!", "name": "badb64"},
+		{"path": "/", "request_method": "get", "keyword": ["a"], "root_path": "x", "name": "badroot"},
+		{"path": "/", "keyword": ["a"], "name": "nomethod"},
+		{"path": "/admin", "request_method": "get", "keyword": ["a"], "favicon_hash": ["1"], "name": "fav"},
+		{"path": "/", "request_method": "get", "any_of": [{"keyword": ["a"]}, {}], "name": "anyempty"},
+		{"path": "/", "request_method": "get", "none_of": [{"keyword": ["a"]}], "name": "ok"},
+		{"path": "/login", "request_method": "po st", "keyword": ["a"], "name": "badmethod"},
+		{"path": "/login", "request_method": "gett", "keyword": ["a"], "name": "oddmethod"},
+		{"path": "/login", "request_method": "post", "keyword": ["a"], "name": "post"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type issue struct {
+		Index int
+		Level string
+	}
+	var got []issue
+	for _, i := range issues {
+		got = append(got, issue{i.Index, i.Level})
+	}
+	want := []issue{
+		{0, LintError},
+		{2, LintWarning},
+		{3, LintWarning},
+		{4, LintError},
+		{5, LintError},
+		{6, LintWarning},
+		{7, LintWarning},
+		{7, LintWarning},
+		{8, LintError},
+		{10, LintError},
+		{11, LintWarning},
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("got %v; want %v; diff: %v", issues, want, diff)
+	}
+	if !HasLintError(issues) {
+		t.Error("HasLintError = false; want true")
+	}
+	if _, err := Validate(`{}`); err == nil {
+		t.Error("Validate({}) returns nil error")
+	}
+}
@@ -0,0 +1,106 @@
+package finger
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Metadata 指纹的资产信息，用于资产归类和筛选
+type Metadata struct {
+	Vendor   string   `json:"vendor,omitempty"`   // 厂商
+	Product  string   `json:"product,omitempty"`  // 产品
+	Category string   `json:"category,omitempty"` // 分类，如 CMS、WAF、middleware、VPN、OA
+	Tags     []string `json:"tags,omitempty"`     // 自定义标签
+	CPE      string   `json:"cpe,omitempty"`      // CPE 2.3 格式，如 cpe:2.3:a:apache:tomcat:*:*:*:*:*:*:*:*
+	Severity string   `json:"severity,omitempty"` // 严重程度：info、low、medium、high、critical、unknown
+}
+
+const cpePrefix = "cpe:2.3:"
+
+// Severities 支持的严重程度，和 nuclei 一致
+var Severities = []string{"info", "low", "medium", "high", "critical", "unknown"}
+
+// normalize 检查严重程度和 CPE 格式，严重程度转为小写，并在厂商和产品为空时从 CPE 中补充
+func (m *Metadata) normalize() error {
+	m.Severity = strings.ToLower(strings.TrimSpace(m.Severity))
+	if m.Severity != "" && !slices.Contains(Severities, m.Severity) {
+		return fmt.Errorf("severity %s 不合法，支持：%s", m.Severity, strings.Join(Severities, ", "))
+	}
+	if m.CPE == "" {
+		return nil
+	}
+	parts := strings.Split(m.CPE, ":")
+	if !strings.HasPrefix(m.CPE, cpePrefix) || len(parts) < 5 {
+		return fmt.Errorf("cpe 不是合法的 CPE 2.3 格式: %s", m.CPE)
+	}
+	if m.Vendor == "" && parts[3] != "*" {
+		m.Vendor = parts[3]
+	}
+	if m.Product == "" && parts[4] != "*" {
+		m.Product = parts[4]
+	}
+	return nil
+}
+
+// merge 合并同名指纹的资产信息，已有的字段保持不变，标签取并集
+func (m *Metadata) merge(other Metadata) {
+	if m.Vendor == "" {
+		m.Vendor = other.Vendor
+	}
+	if m.Product == "" {
+		m.Product = other.Product
+	}
+	if m.Category == "" {
+		m.Category = other.Category
+	}
+	if m.CPE == "" {
+		m.CPE = other.CPE
+	}
+	if m.Severity == "" {
+		m.Severity = other.Severity
+	}
+	for _, tag := range other.Tags {
+		if !m.HasTag(tag) {
+			m.Tags = append(m.Tags, tag)
+		}
+	}
+}
+
+func (m Metadata) clone() Metadata {
+	m.Tags = slices.Clone(m.Tags)
+	return m
+}
+
+// HasTag 判断是否包含标签，不区分大小写
+func (m Metadata) HasTag(tag string) bool {
+	return slices.ContainsFunc(m.Tags, func(t string) bool {
+		return strings.EqualFold(t, tag)
+	})
+}
+
+// InCategory 判断是否属于任意一个分类，不区分大小写
+func (m Metadata) InCategory(categories ...string) bool {
+	return slices.ContainsFunc(categories, func(c string) bool {
+		return strings.EqualFold(m.Category, c)
+	})
+}
+
+// FilterResults 按标签和分类筛选识别结果，tags 和 categories 为空时不做对应的筛选
+// 同时指定时需要两者都满足，同一类中满足任意一个即可
+func FilterResults(results []WebFingerResult, tags []string, categories []string) []WebFingerResult {
+	if len(tags) == 0 && len(categories) == 0 {
+		return results
+	}
+	var filtered []WebFingerResult
+	for _, r := range results {
+		if len(tags) != 0 && !slices.ContainsFunc(tags, r.HasTag) {
+			continue
+		}
+		if len(categories) != 0 && !r.InCategory(categories...) {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
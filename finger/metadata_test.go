@@ -0,0 +1,96 @@
+package finger
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestMetadata(t *testing.T) {
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "request_method": "get", "keyword": ["seeyon"], "name": "Seeyon OA",
+		 "category": "OA", "tags": ["china"], "cpe": "cpe:2.3:a:seeyon:a8:*:*:*:*:*:*:*:*", "implies": ["Java"]},
+		{"path": "/", "request_method": "get", "keyword": ["/seeyon/"], "name": "Seeyon OA", "tags": ["CHINA", "oa"], "severity": "Medium"},
+		{"path": "/", "request_method": "get", "keyword": ["jsessionid"], "name": "Java", "category": "Programming languages"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := wfs.Resolve(wfs.MatchIndexResponse(&Response{
+		StatusCode: http.StatusOK,
+		Body:       []byte(`<a href="/seeyon/index.jsp">`),
+	}))
+	var got []Metadata
+	for _, r := range results {
+		got = append(got, r.Metadata)
+	}
+	want := []Metadata{
+		{Vendor: "seeyon", Product: "a8", Category: "OA", Tags: []string{"china", "oa"}, CPE: "cpe:2.3:a:seeyon:a8:*:*:*:*:*:*:*:*", Severity: "medium"},
+		{Category: "Programming languages"},
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Error(diff)
+	}
+
+	filterTests := []struct {
+		tags       []string
+		categories []string
+		want       int
+	}{
+		{nil, nil, 2},
+		{[]string{"OA"}, nil, 1},
+		{[]string{"oa"}, []string{"cms"}, 0},
+		{nil, []string{"cms", "programming languages"}, 1},
+	}
+	for _, tt := range filterTests {
+		if got := FilterResults(results, tt.tags, tt.categories); len(got) != tt.want {
+			t.Errorf("FilterResults(%v, %v) = %d results; want %d", tt.tags, tt.categories, len(got), tt.want)
+		}
+	}
+
+	if _, err := ParseWebFinger(`[{"path": "/", "request_method": "get", "keyword": ["a"], "name": "bad", "cpe": "cpe:/a:x:y"}]`); err == nil {
+		t.Error("ParseWebFinger with invalid cpe: want error")
+	}
+	if _, err := ParseWebFinger(`[{"path": "/", "request_method": "get", "keyword": ["a"], "name": "bad", "severity": "urgent"}]`); err == nil {
+		t.Error("ParseWebFinger with invalid severity: want error")
+	}
+}
+
+func TestImporterMetadata(t *testing.T) {
+	tests := []struct {
+		format  string
+		content string
+		want    Metadata
+	}{
+		{
+			"wappalyzer",
+			`{"technologies": {"WordPress": {"cats": [1, 11, 99], "html": ["wp-content"], "cpe": "cpe:2.3:a:wordpress:wordpress:*:*:*:*:*:*:*:*"}},
+			  "categories": {"1": {"name": "CMS"}, "11": {"name": "Blogs"}}}`,
+			Metadata{Vendor: "wordpress", Product: "wordpress", Category: "CMS", Tags: []string{"CMS", "Blogs"}, CPE: "cpe:2.3:a:wordpress:wordpress:*:*:*:*:*:*:*:*"},
+		},
+		{
+			"nuclei",
+			"id: tomcat-detect\ninfo:\n  name: Tomcat\n  severity: High\n  tags: tech, apache,tomcat\n  metadata:\n    vendor: apache\n" +
+				"http:\n  - path: [\"{{BaseURL}}\"]\n    matchers:\n      - type: word\n        words: [Tomcat]\n",
+			Metadata{Vendor: "apache", Tags: []string{"tech", "apache", "tomcat"}, Severity: "high"},
+		},
+		{
+			"goby",
+			`[{"product": "Fortinet-FortiGate", "company": "Fortinet", "category": "Firewall", "rule": [[{"match": "body_contains", "content": "fortinet"}]]}]`,
+			Metadata{Vendor: "Fortinet", Product: "Fortinet-FortiGate", Category: "Firewall"},
+		},
+	}
+	for _, tt := range tests {
+		wfs, err := ParseWebFingerFormat(tt.format, tt.content)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if len(wfs.Indexs) != 1 {
+			t.Fatalf("%s: len(Indexs) = %d; want 1", tt.format, len(wfs.Indexs))
+		}
+		if diff := deep.Equal(wfs.Indexs[0].Metadata, tt.want); diff != nil {
+			t.Errorf("%s: %v", tt.format, diff)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package finger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NucleiTemplate 类 nuclei 的 YAML 指纹模板，只支持不依赖 DSL 的 http 请求
+// 一个文件中可以使用 --- 分隔多个模板，不支持的模板会被跳过
+type NucleiTemplate struct {
+	ID   string `yaml:"id"`
+	Info struct {
+		Name     string     `yaml:"name"`
+		Tags     nucleiTags `yaml:"tags"`
+		Severity string     `yaml:"severity"`
+		// 值可能是列表，如 shodan-query，只读取字符串类型的 vendor 和 product
+		Metadata       map[string]any `yaml:"metadata"`
+		Classification struct {
+			CPE string `yaml:"cpe"`
+		} `yaml:"classification"`
+	} `yaml:"info"`
+	HTTP     []NucleiRequest `yaml:"http"`
+	Requests []NucleiRequest `yaml:"requests"` // 旧版本模板使用 requests
+}
+
+// nucleiTags 兼容逗号分隔的字符串和列表两种写法
+type nucleiTags []string
+
+func (t *nucleiTags) UnmarshalYAML(value *yaml.Node) error {
+	var l []string
+	if value.Kind == yaml.SequenceNode {
+		if err := value.Decode(&l); err != nil {
+			return err
+		}
+	} else {
+		var s string
+		if err := value.Decode(&s); err != nil {
+			return err
+		}
+		l = strings.Split(s, ",")
+	}
+	for _, tag := range l {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+// NucleiRequest 模板中的一个请求，path 中的每个路径都会生成一条指纹
+type NucleiRequest struct {
+	Method            string            `yaml:"method"`
+	Path              []string          `yaml:"path"`
+	Raw               []string          `yaml:"raw"`
+	Headers           map[string]string `yaml:"headers"`
+	Body              string            `yaml:"body"`
+	MatchersCondition string            `yaml:"matchers-condition"` // and、or（默认）
+	Matchers          []NucleiMatcher   `yaml:"matchers"`
+	Extractors        []NucleiExtractor `yaml:"extractors"`
+}
+
+// NucleiMatcher 匹配器，支持 word、regex、status
+type NucleiMatcher struct {
+	Type      string   `yaml:"type"`
+	Part      string   `yaml:"part"` // body（默认）、header、all
+	Words     []string `yaml:"words"`
+	Regex     []string `yaml:"regex"`
+	Status    []int    `yaml:"status"`
+	Condition string   `yaml:"condition"` // 同一个匹配器中多个值的关系：and、or（默认）
+	Negative  bool     `yaml:"negative"`
+	// word 匹配器默认区分大小写，为 true 时不区分
+	CaseInsensitive bool `yaml:"case-insensitive"`
+}
+
+// kvalVersionRegex 从 kval 提取的响应头中提取版本号，如 nginx/1.24.0 中的 1.24.0
+var kvalVersionRegex = regexp.MustCompile(`(?:^|[/\s])v?(\d[\w.-]*)`)
+
+// NucleiExtractor 提取器，提取到的值作为指纹版本，支持 regex、kval
+// kval 只提取响应头中的版本号部分，而不是整个值
+type NucleiExtractor struct {
+	Type  string   `yaml:"type"`
+	Part  string   `yaml:"part"`
+	Regex []string `yaml:"regex"`
+	Group int      `yaml:"group"`
+	Kval  []string `yaml:"kval"`
+}
+
+// nucleiScope 把 part 转为关键词匹配范围
+func nucleiScope(part string) (string, error) {
+	switch strings.ToLower(part) {
+	case "", "body":
+		return ScopeBody, nil
+	case "header", "all_headers":
+		return ScopeHeader, nil
+	case "all", "response", "raw":
+		return ScopeRaw, nil
+	}
+	return "", fmt.Errorf("不支持的 part %s", part)
+}
+
+// combineRules 按照 and/or 组合多个子规则
+func combineRules(condition string, rules []MatchRule) (MatchRule, error) {
+	if len(rules) == 1 {
+		return rules[0], nil
+	}
+	switch strings.ToLower(condition) {
+	case "", "or":
+		return MatchRule{AnyOf: rules}, nil
+	case "and":
+		return MatchRule{AllOf: rules}, nil
+	}
+	return MatchRule{}, fmt.Errorf("不支持的 condition %s", condition)
+}
+
+func (nm *NucleiMatcher) toMatchRule() (mr MatchRule, err error) {
+	var rules []MatchRule
+	switch strings.ToLower(nm.Type) {
+	case "word":
+		scope, err := nucleiScope(nm.Part)
+		if err != nil {
+			return mr, err
+		}
+		// 和 nuclei 一致，默认区分大小写
+		for _, word := range nm.Words {
+			rules = append(rules, MatchRule{Keyword: []string{word}, Scope: scope, CaseSensitive: !nm.CaseInsensitive})
+		}
+	case "regex":
+		scope, err := nucleiScope(nm.Part)
+		if err != nil {
+			return mr, err
+		}
+		// 正则按原样编译，需要忽略大小写时在正则中使用 (?i)
+		for _, expr := range nm.Regex {
+			re, err := compileRegexCase(expr, true)
+			if err != nil {
+				return mr, fmt.Errorf("正则不合法: %w", err)
+			}
+			rules = append(rules, MatchRule{KeywordRegex: []*regexp.Regexp{re}, Scope: scope, CaseSensitive: true})
+		}
+	case "status":
+		// 状态码之间只能是或关系，使用一个状态码集合表示
+		var codes []string
+		for _, code := range nm.Status {
+			codes = append(codes, strconv.Itoa(code))
+		}
+		if len(codes) != 0 {
+			sc, err := ParseStatusCodes(codes...)
+			if err != nil {
+				return mr, err
+			}
+			rules = append(rules, MatchRule{StatusCode: sc})
+		}
+	default:
+		return mr, fmt.Errorf("不支持的匹配器类型 %s", nm.Type)
+	}
+	if len(rules) == 0 {
+		return mr, fmt.Errorf("%s 匹配器没有任何值", nm.Type)
+	}
+	if mr, err = combineRules(nm.Condition, rules); err != nil {
+		return mr, err
+	}
+	if nm.Negative {
+		mr = MatchRule{NoneOf: []MatchRule{mr}}
+	}
+	return mr, nil
+}
+
+func (ne *NucleiExtractor) toVersionExtractors() ([]VersionExtractor, error) {
+	var versions []VersionExtractor
+	switch strings.ToLower(ne.Type) {
+	case "regex":
+		scope, err := nucleiScope(ne.Part)
+		if err != nil {
+			return nil, err
+		}
+		for _, expr := range ne.Regex {
+			// part 为 header 时从所有响应头中提取
+			ve, err := newVersionExtractor(scope, "", expr, ne.Group)
+			if err != nil {
+				return nil, err
+			}
+			versions = append(versions, ve)
+		}
+	case "kval":
+		for _, name := range ne.Kval {
+			versions = append(versions, VersionExtractor{
+				From:  VersionFromHeader,
+				Name:  strings.ReplaceAll(name, "_", "-"),
+				Regex: kvalVersionRegex,
+				Group: 1,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("不支持的提取器类型 %s", ne.Type)
+	}
+	return versions, nil
+}
+
+// nucleiPath 去掉路径中的 {{BaseURL}}、{{RootURL}} 占位符
+func nucleiPath(p string) string {
+	p = strings.TrimPrefix(p, "{{BaseURL}}")
+	p = strings.TrimPrefix(p, "{{RootURL}}")
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+// metadata 转换 info 中的 tags、severity、metadata.vendor、metadata.product 和 classification.cpe
+func (nt *NucleiTemplate) metadata() (Metadata, error) {
+	vendor, _ := nt.Info.Metadata["vendor"].(string)
+	product, _ := nt.Info.Metadata["product"].(string)
+	m := Metadata{
+		Vendor:   vendor,
+		Product:  product,
+		Tags:     nt.Info.Tags,
+		CPE:      nt.Info.Classification.CPE,
+		Severity: nt.Info.Severity,
+	}
+	return m, m.normalize()
+}
+
+func (nr *NucleiRequest) toWebFingers(name string, metadata Metadata) ([]WebFinger, error) {
+	if len(nr.Raw) != 0 {
+		return nil, fmt.Errorf("不支持 raw 请求")
+	}
+	if len(nr.Matchers) == 0 {
+		return nil, fmt.Errorf("请求没有任何匹配器")
+	}
+	var rules []MatchRule
+	for i := range nr.Matchers {
+		mr, err := nr.Matchers[i].toMatchRule()
+		if err != nil {
+			return nil, fmt.Errorf("matchers[%d] %w", i, err)
+		}
+		rules = append(rules, mr)
+	}
+	matchRules, err := combineRules(nr.MatchersCondition, rules)
+	if err != nil {
+		return nil, err
+	}
+	var versions []VersionExtractor
+	for i := range nr.Extractors {
+		ves, err := nr.Extractors[i].toVersionExtractors()
+		if err != nil {
+			return nil, fmt.Errorf("extractors[%d] %w", i, err)
+		}
+		versions = append(versions, ves...)
+	}
+	method := strings.ToLower(nr.Method)
+	if method == "" {
+		method = "get"
+	}
+	var wfs []WebFinger
+	for _, p := range nr.Path {
+		wfs = append(wfs, WebFinger{
+			Metadata: metadata.clone(),
+			Name:     name,
+			Request: RequestInfo{
+				Path:          nucleiPath(p),
+				RequestMethod: method,
+				RequestHeader: nr.Headers,
+				RequestData:   []byte(nr.Body),
+			},
+			MatchRules: matchRules,
+			RootPath:   "/",
+			Version:    versions,
+		})
+	}
+	return wfs, nil
+}
+
+// decodeNucleiTemplates 解码文件中的所有 YAML 模板
+func decodeNucleiTemplates(content string) ([]NucleiTemplate, error) {
+	var templates []NucleiTemplate
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var nt NucleiTemplate
+		err := decoder.Decode(&nt)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, nt)
+	}
+	return templates, nil
+}
+
+func isNuclei(content string) bool {
+	// json 也是合法的 yaml，提前排除
+	if c := bytes.TrimSpace([]byte(content)); len(c) == 0 || c[0] == '[' || c[0] == '{' {
+		return false
+	}
+	templates, err := decodeNucleiTemplates(content)
+	if err != nil || len(templates) == 0 {
+		return false
+	}
+	return templates[0].ID != "" && len(templates[0].HTTP)+len(templates[0].Requests) != 0
+}
+
+// toWebFingers 转换模板中所有请求，任意一个请求不支持时整个模板都不可用
+func (nt *NucleiTemplate) toWebFingers() ([]WebFinger, error) {
+	name := nt.Info.Name
+	if name == "" {
+		name = nt.ID
+	}
+	metadata, err := nt.metadata()
+	if err != nil {
+		return nil, err
+	}
+	var fingers []WebFinger
+	for i, nr := range append(nt.HTTP, nt.Requests...) {
+		wfs, err := nr.toWebFingers(name, metadata)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个请求 %w", i+1, err)
+		}
+		fingers = append(fingers, wfs...)
+	}
+	return fingers, nil
+}
+
+// ParseNuclei 解析类 nuclei 的 YAML 指纹模板
+// 每个请求路径生成一条指纹，路径为 {{BaseURL}} 或 / 的 GET 请求作为首页指纹，其余作为自定义请求指纹
+// 不支持的模板（如使用 dsl 匹配器或 raw 请求）会被跳过并记录在 Warnings 中，所有模板都不支持时返回 error
+func ParseNuclei(content string) (*WebFingerSystem, error) {
+	templates, err := decodeNucleiTemplates(content)
+	if err != nil {
+		return nil, err
+	}
+	wfs := new(WebFingerSystem)
+	for _, nt := range templates {
+		fingers, err := nt.toWebFingers()
+		if err != nil {
+			wfs.Warnings = append(wfs.Warnings, fmt.Sprintf("跳过模板 %s: %v", nt.ID, err))
+			continue
+		}
+		for _, wf := range fingers {
+			if wf.IsIndex() {
+				wfs.Indexs = append(wfs.Indexs, wf)
+			} else {
+				wfs.CustomReqs = append(wfs.CustomReqs, wf)
+			}
+		}
+	}
+	if wfs.Count() == 0 && len(wfs.Warnings) != 0 {
+		return nil, fmt.Errorf("没有可用的模板，%s", strings.Join(wfs.Warnings, "; "))
+	}
+	wfs.Build()
+	return wfs, nil
+}
@@ -0,0 +1,130 @@
+package finger
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+const testNucleiContent = `id: nacos-detect
+info:
+  name: Nacos
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/nacos/"
+      - "{{BaseURL}}/nacos/index.html"
+    matchers-condition: and
+    matchers:
+      - type: word
+        words:
+          - "<title>Nacos</title>"
+      - type: status
+        status: [200, 302]
+      - type: word
+        part: header
+        words: ["X-Honeypot"]
+        negative: true
+    extractors:
+      - type: regex
+        part: body
+        regex:
+          - 'version":"([\d.]+)"'
+---
+id: nginx-detect
+info:
+  name: nginx
+requests:
+  - path:
+      - "{{BaseURL}}"
+    matchers:
+      - type: regex
+        part: header
+        regex: ["(?m)^Server: nginx"]
+    extractors:
+      - type: kval
+        kval: [server]
+`
+
+func TestParseNuclei(t *testing.T) {
+	wfs, err := ParseWebFingerFormat(FormatAuto, testNucleiContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wfs.Indexs) != 1 || len(wfs.CustomReqs) != 2 {
+		t.Fatalf("len(Indexs), len(CustomReqs) = %d, %d; want 1, 2", len(wfs.Indexs), len(wfs.CustomReqs))
+	}
+	crs := wfs.CustomRequests()
+	tests := []struct {
+		resp *Response
+		want []string
+	}{
+		{&Response{StatusCode: 200, Body: []byte(`<title>Nacos</title>{"version":"2.2.3"}`)}, []string{"Nacos 2.2.3"}},
+		{&Response{StatusCode: 302, Body: []byte(`<title>Nacos</title>`)}, []string{"Nacos"}},
+		{&Response{StatusCode: 404, Body: []byte(`<title>Nacos</title>`)}, nil},
+		// word 匹配器默认区分大小写
+		{&Response{StatusCode: 200, Body: []byte(`<title>NACOS</title>`)}, nil},
+		{&Response{StatusCode: 200, Body: []byte(`<title>Nacos</title>`), Header: http.Header{"X-Honeypot": {"1"}}}, nil},
+	}
+	for _, tc := range tests {
+		var got []string
+		for _, r := range wfs.MatchCustom(&crs[0], tc.resp) {
+			got = append(got, r.String())
+		}
+		if diff := deep.Equal(got, tc.want); diff != nil {
+			t.Errorf("MatchCustom(%d, %s) diff: %v", tc.resp.StatusCode, tc.resp.Body, diff)
+		}
+	}
+	res := wfs.MatchIndexResponse(&Response{Header: http.Header{"Server": {"nginx/1.24.0"}}})
+	if len(res) != 1 || res[0].String() != "nginx 1.24.0" {
+		t.Errorf("MatchIndex = %v; want [nginx 1.24.0]", res)
+	}
+	if _, err := ParseNuclei("id: x\nhttp:\n  - raw: ['GET / HTTP/1.1']\n"); err == nil {
+		t.Error("ParseNuclei with raw request returns nil error")
+	}
+}
+
+func TestParseNucleiSkipUnsupported(t *testing.T) {
+	content := `id: dsl-detect
+info:
+  name: dsl
+http:
+  - path: ["{{BaseURL}}"]
+    matchers:
+      - type: dsl
+        dsl: ["status_code == 200"]
+---
+id: grafana-detect
+info:
+  name: Grafana
+  metadata:
+    vendor: grafana
+    product: grafana
+    shodan-query:
+      - title:"Grafana"
+      - http.favicon.hash:2123863676
+    max-request: 1
+http:
+  - path: ["{{BaseURL}}"]
+    matchers:
+      - type: word
+        words: ["grafana-app"]
+`
+	wfs, err := ParseNuclei(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wfs.Indexs) != 1 || wfs.Indexs[0].Vendor != "grafana" || wfs.Indexs[0].Product != "grafana" {
+		t.Fatalf("Indexs = %+v; want grafana", wfs.Indexs)
+	}
+	if len(wfs.Warnings) != 1 || !strings.Contains(wfs.Warnings[0], "dsl-detect") {
+		t.Errorf("Warnings = %v; want dsl-detect skipped", wfs.Warnings)
+	}
+	issues := ValidateSystem(wfs)
+	if len(issues) != 1 || issues[0].Level != LintWarning || issues[0].Index != -1 {
+		t.Errorf("ValidateSystem = %v; want one warning", issues)
+	}
+}
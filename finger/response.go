@@ -0,0 +1,264 @@
+package finger
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/akkuman/webeye/utils"
+	"golang.org/x/net/html"
+)
+
+// Response 指纹匹配使用的响应数据
+// 一个 Response 会被所有指纹共享，派生数据（小写正文、响应头 map 等）只会计算一次
+type Response struct {
+	URL            string             // 当前 URL
+	Proto          string             // 协议版本，如 HTTP/1.1，为空时按 HTTP/1.1 处理
+	StatusCode     int                // 状态码
+	Header         http.Header        // 响应头
+	Body           []byte             // 响应体
+	Title          string             // 标题
+	Favicons       []string           // 图标 hash 列表
+	FaviconPHashes []string           // 图标的感知哈希列表，见 utils.PerceptualHash
+	BodyHashes     []string           // 响应体的 md5 和 mmh3，为空时根据 Body 计算
+	Certs          []x509.Certificate // TLS 证书链，非 https 响应为空
+
+	bodyHashOnce  sync.Once
+	headerMapOnce sync.Once
+	headerMap     map[string]string
+	headerValues  map[string]string
+	cookiesOnce   sync.Once
+	cookies       []*http.Cookie
+	documentOnce  sync.Once
+	document      *goquery.Document
+	jsonOnce      sync.Once
+	json          any
+	isJSON        bool
+	bodyOnce      sync.Once
+	bodyText      string
+	lowerBodyOnce sync.Once
+	lowerBody     string
+	headerOnce    sync.Once
+	headerText    string
+	lowerHeader   string
+	rawText       scopeText
+	scriptText    scopeText
+	commentText   scopeText
+	// 关键词自动机及其扫描结果，由 WebFingerSystem 设置
+	keywordMatcher *keywordMatcher
+	keywordHits    []bool
+}
+
+// scopeText 按需构建一次的匹配范围文本及其小写形式
+type scopeText struct {
+	once  sync.Once
+	text  string
+	lower string
+}
+
+func (st *scopeText) get(build func() string) *scopeText {
+	st.once.Do(func() {
+		st.text = build()
+		st.lower = strings.ToLower(st.text)
+	})
+	return st
+}
+
+// HeaderMap 返回键值都为小写的响应头，详见 HTTPHeadersToMap
+func (r *Response) HeaderMap() map[string]string {
+	r.headerMapOnce.Do(r.buildHeaderMap)
+	return r.headerMap
+}
+
+// HeaderValues 返回键为小写、值保留原始大小写的响应头，同名响应头的值以 "; " 连接
+func (r *Response) HeaderValues() map[string]string {
+	r.headerMapOnce.Do(r.buildHeaderMap)
+	return r.headerValues
+}
+
+func (r *Response) buildHeaderMap() {
+	r.headerMap = HTTPHeadersToMap(r.Header)
+	r.headerValues = make(map[string]string, len(r.Header))
+	for k, v := range r.Header {
+		r.headerValues[strings.ToLower(k)] = strings.Join(v, "; ")
+	}
+}
+
+// Cookies 返回从 Set-Cookie 响应头中解析出的 cookie，不合法的 Set-Cookie 会被忽略
+func (r *Response) Cookies() []*http.Cookie {
+	r.cookiesOnce.Do(func() {
+		r.cookies = (&http.Response{Header: r.Header}).Cookies()
+	})
+	return r.cookies
+}
+
+// Document 返回解析后的 HTML 文档，所有选择器条件共享同一次解析，解析失败时返回 nil
+func (r *Response) Document() *goquery.Document {
+	r.documentOnce.Do(func() {
+		r.document, _ = goquery.NewDocumentFromReader(bytes.NewReader(r.Body))
+	})
+	return r.document
+}
+
+// JSON 返回解析后的 json 响应体，所有 json 条件共享同一次解析，响应体不是 json 对象或数组时返回 false
+func (r *Response) JSON() (any, bool) {
+	r.jsonOnce.Do(func() {
+		r.json, r.isJSON = utils.ParseJSON(r.Body)
+	})
+	return r.json, r.isJSON
+}
+
+// matchBodyHash 返回第一个和响应体 hash 相同的值，hash 不区分大小写
+func (r *Response) matchBodyHash(hashes []string) (string, bool) {
+	r.bodyHashOnce.Do(func() {
+		if len(r.BodyHashes) == 0 {
+			r.BodyHashes = []string{utils.MD5Hex(r.Body), utils.MMH3Hash(r.Body)}
+		}
+	})
+	for _, h := range hashes {
+		for _, bh := range r.BodyHashes {
+			if strings.EqualFold(h, bh) {
+				return bh, true
+			}
+		}
+	}
+	return "", false
+}
+
+// findCookie 返回第一个名称相同（不区分大小写）且值满足 matchValue 的 cookie
+func (r *Response) findCookie(name string, matchValue func(value string) bool) *http.Cookie {
+	for _, c := range r.Cookies() {
+		if strings.EqualFold(c.Name, name) && matchValue(c.Value) {
+			return c
+		}
+	}
+	return nil
+}
+
+// LowerBody 返回小写的响应体文本
+func (r *Response) LowerBody() string {
+	r.lowerBodyOnce.Do(func() {
+		r.lowerBody = strings.ToLower(r.ScopeText(ScopeBody))
+	})
+	return r.lowerBody
+}
+
+// HeaderText 返回所有响应头的文本，按名称排序，每行一个，格式为 Name: value
+func (r *Response) HeaderText() string {
+	r.headerOnce.Do(func() {
+		var sb strings.Builder
+		for _, k := range sortedKeys(r.Header) {
+			for _, v := range r.Header[k] {
+				sb.WriteString(k + ": " + v + "\r\n")
+			}
+		}
+		r.headerText = sb.String()
+		r.lowerHeader = strings.ToLower(r.headerText)
+	})
+	return r.headerText
+}
+
+// ScopeText 返回匹配范围对应的原始文本
+func (r *Response) ScopeText(scope string) string {
+	switch scope {
+	case ScopeHeader:
+		return r.HeaderText()
+	case ScopeRaw, ScopeScript, ScopeComment:
+		return r.extraScopeText(scope).text
+	}
+	r.bodyOnce.Do(func() {
+		r.bodyText = string(r.Body)
+	})
+	return r.bodyText
+}
+
+// lowerScopeText 返回匹配范围对应的小写文本
+func (r *Response) lowerScopeText(scope string) string {
+	switch scope {
+	case ScopeHeader:
+		r.HeaderText()
+		return r.lowerHeader
+	case ScopeRaw, ScopeScript, ScopeComment:
+		return r.extraScopeText(scope).lower
+	}
+	return r.LowerBody()
+}
+
+func (r *Response) extraScopeText(scope string) *scopeText {
+	switch scope {
+	case ScopeRaw:
+		return r.rawText.get(r.buildRawText)
+	case ScopeScript:
+		return r.scriptText.get(func() string { return r.nodeText(html.ElementNode, "script") })
+	default:
+		return r.commentText.get(func() string { return r.nodeText(html.CommentNode, "") })
+	}
+}
+
+// buildRawText 拼接状态行、响应头和响应体
+func (r *Response) buildRawText() string {
+	proto := r.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	return fmt.Sprintf("%s %d %s\r\n%s\r\n%s", proto, r.StatusCode, http.StatusText(r.StatusCode), r.HeaderText(), r.ScopeText(ScopeBody))
+}
+
+// nodeText 返回文档中所有 tag 元素的内容，nodeType 为 html.CommentNode 时返回所有注释的内容，每个节点一行
+func (r *Response) nodeText(nodeType html.NodeType, tag string) string {
+	doc := r.Document()
+	if doc == nil {
+		return ""
+	}
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == nodeType && (nodeType == html.CommentNode || n.Data == tag) {
+			if nodeType == html.CommentNode {
+				sb.WriteString(n.Data)
+			} else {
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					sb.WriteString(c.Data)
+				}
+			}
+			sb.WriteString("\n")
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range doc.Nodes {
+		walk(n)
+	}
+	return sb.String()
+}
+
+// useKeywordMatcher 设置关键词自动机，正文会在第一次匹配关键词时扫描一次
+func (r *Response) useKeywordMatcher(m *keywordMatcher) {
+	if r.keywordMatcher != m {
+		r.keywordMatcher = m
+		r.keywordHits = nil
+	}
+}
+
+// containsKeyword 判断匹配范围内是否包含关键词，caseSensitive 为 false 时不区分大小写
+// 响应体中不区分大小写的关键词在自动机中时直接查询扫描结果，否则退化为 strings.Contains
+func (r *Response) containsKeyword(keyword string, scope string, caseSensitive bool) bool {
+	if caseSensitive {
+		return strings.Contains(r.ScopeText(scope), keyword)
+	}
+	if (scope == "" || scope == ScopeBody) && r.keywordMatcher != nil {
+		if id, ok := r.keywordMatcher.ids[keyword]; ok {
+			if r.keywordHits == nil {
+				r.keywordHits = r.keywordMatcher.match(r.LowerBody())
+			}
+			return r.keywordHits[id]
+		}
+	}
+	return strings.Contains(r.lowerScopeText(scope), strings.ToLower(keyword))
+}
@@ -0,0 +1,127 @@
+package finger
+
+import (
+	"cmp"
+	"slices"
+)
+
+// 指纹的命中方式
+const (
+	MatchKindIndex   = "index"   // 首页指纹
+	MatchKindFavicon = "favicon" // 图标指纹
+	MatchKindCustom  = "custom"  // 自定义请求指纹
+	MatchKindImplied = "implied" // 由其他指纹的 implies 推导
+)
+
+// 命中条件的类型
+const (
+	ConditionStatusCode    = "status_code"
+	ConditionContentLength = "content_length"
+	ConditionBodyHash      = "body_hash"
+	ConditionHeader        = "header"
+	ConditionHeaderRegex   = "header_regex"
+	ConditionKeyword       = "keyword"
+	ConditionKeywordRegex  = "keyword_regex"
+	ConditionTitle         = "title"
+	ConditionTitleRegex    = "title_regex"
+	ConditionCookie        = "cookie"
+	ConditionCookieRegex   = "cookie_regex"
+	ConditionSelector      = "selector"
+	ConditionJSON          = "json"
+	ConditionCert          = "cert"
+	ConditionFavicon       = "favicon"
+	ConditionFaviconPHash  = "favicon_phash"
+	ConditionImpliedBy     = "implied_by"
+)
+
+// Condition 命中的具体条件
+type Condition struct {
+	Type  string // 条件类型
+	Value string // 命中的关键词、响应头、图标 hash 等，正则条件为正则实际匹配到的文本
+}
+
+// Evidence 指纹命中的证据
+type Evidence struct {
+	URL        string      // 命中的 URL，首页指纹可能是跳转链中的任意一个
+	Kind       string      // 命中方式：index、favicon、custom
+	Conditions []Condition // 命中的条件
+	Confidence float64     // 本次命中的置信度，0~1
+}
+
+// WebFingerResult 指纹识别结果
+type WebFingerResult struct {
+	Metadata   // 资产信息
+	Name       string
+	RootPath   string
+	Version    string
+	Priority   int        // 指纹优先度，合并时取最高值
+	Confidence float64    // 置信度，0~1，由所有命中证据的置信度汇总得到
+	Evidences  []Evidence // 命中证据，同一个指纹可能被多个规则或多个页面命中
+}
+
+func NewWebFingerResult(wf WebFinger) WebFingerResult {
+	return WebFingerResult{
+		Metadata: wf.Metadata.clone(),
+		Name:     wf.Name,
+		RootPath: wf.RootPath,
+		Priority: wf.Priority,
+	}
+}
+
+// String 返回指纹名称，有版本时附带版本，如 nginx 1.18.0
+func (r WebFingerResult) String() string {
+	if r.Version == "" {
+		return r.Name
+	}
+	return r.Name + " " + r.Version
+}
+
+// resultKey 同一站点路径下的同名指纹
+type resultKey struct {
+	Name     string
+	RootPath string
+}
+
+// MergeResults 合并同一站点路径下的同名指纹，保留最先提取到的版本并汇总命中证据和资产信息，优先度取最高值，并重新计算置信度，结果顺序和首次出现的顺序一致
+func MergeResults(results []WebFingerResult) []WebFingerResult {
+	merged := make([]WebFingerResult, 0, len(results))
+	index := make(map[resultKey]int)
+	for _, r := range results {
+		key := resultKey{r.Name, r.RootPath}
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			r.Evidences = slices.Clone(r.Evidences)
+			r.Metadata = r.Metadata.clone()
+			r.Confidence = resultConfidence(r.Evidences)
+			merged = append(merged, r)
+			continue
+		}
+		if merged[i].Version == "" {
+			merged[i].Version = r.Version
+		}
+		merged[i].Priority = max(merged[i].Priority, r.Priority)
+		merged[i].Metadata.merge(r.Metadata)
+		for _, e := range r.Evidences {
+			if !slices.ContainsFunc(merged[i].Evidences, e.equal) {
+				merged[i].Evidences = append(merged[i].Evidences, e)
+			}
+		}
+		merged[i].Confidence = resultConfidence(merged[i].Evidences)
+	}
+	return merged
+}
+
+// SortResults 按优先度从高到低排序，优先度相同时按置信度从高到低排序，都相同时保持原有顺序
+func SortResults(results []WebFingerResult) {
+	slices.SortStableFunc(results, func(a, b WebFingerResult) int {
+		if a.Priority != b.Priority {
+			return b.Priority - a.Priority
+		}
+		return cmp.Compare(b.Confidence, a.Confidence)
+	})
+}
+
+func (e Evidence) equal(other Evidence) bool {
+	return e.URL == other.URL && e.Kind == other.Kind && slices.Equal(e.Conditions, other.Conditions)
+}
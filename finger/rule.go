@@ -0,0 +1,385 @@
+package finger
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// 关键词的匹配范围
+const (
+	ScopeBody    = "body"    // 响应体
+	ScopeHeader  = "header"  // 所有响应头，每行一个，格式为 Name: value
+	ScopeRaw     = "raw"     // 完整响应：状态行、响应头、空行和响应体
+	ScopeScript  = "script"  // 所有 script 标签的内容
+	ScopeComment = "comment" // 所有 HTML 注释的内容
+)
+
+var scopes = []string{"", ScopeBody, ScopeHeader, ScopeRaw, ScopeScript, ScopeComment}
+
+// MatchRule 匹配规则
+// 同一层级的所有条件需要全部满足，AllOf/AnyOf/NoneOf 可以嵌套，用于表达与、或、非的关系
+type MatchRule struct {
+	StatusCode  StatusCodes `json:"status_code"`  // 匹配状态码，支持列表、范围和排除
+	FaviconHash []string    `json:"favicon_hash"` // 匹配图标 hash，一个匹配到了就算命中
+	// 匹配图标的感知哈希，和任意一个图标的汉明距离不超过 FaviconPHashDistance 就算命中
+	FaviconPHash         []uint64 `json:"favicon_phash"`
+	FaviconPHashDistance int      `json:"favicon_phash_distance"`
+	BodyHash             []string `json:"body_hash"` // 匹配响应体的 md5 或 mmh3，一个匹配到了就算命中
+	// 匹配响应体长度，支持精确长度和范围
+	ContentLength ContentLengths    `json:"content_length"`
+	Headers       map[string]string `json:"headers"` // 匹配全球头，读取键，匹配值，如果值为*或者空，只匹配键
+	Keyword       []string          `json:"keyword"` // 匹配关键词
+	// 匹配响应头正则，读取键，使用正则匹配值，默认不区分大小写
+	HeadersRegex map[string]*regexp.Regexp `json:"headers_regex"`
+	// 匹配正则关键词，默认不区分大小写
+	KeywordRegex []*regexp.Regexp `json:"keyword_regex"`
+	// 匹配提取出的标题，需要包含所有关键词，不区分大小写
+	Title []string `json:"title"`
+	// 标题去掉首尾空白后需要和该值相等，不区分大小写
+	TitleEquals string `json:"title_equals"`
+	// 匹配标题的正则，默认不区分大小写
+	TitleRegex []*regexp.Regexp `json:"title_regex"`
+	// 匹配 Set-Cookie 中的 cookie，键为 cookie 名称（不区分大小写），值需要完全相等，值为*或者空时只匹配名称
+	Cookies map[string]string `json:"cookies"`
+	// 匹配 cookie 值的正则，键为 cookie 名称，默认不区分大小写
+	CookiesRegex map[string]*regexp.Regexp `json:"cookies_regex"`
+	// 使用 CSS 选择器匹配解析后的 HTML 文档，需要全部满足
+	Selectors []SelectorRule `json:"selectors"`
+	// 把响应体解析为 json 后按路径表达式匹配，需要全部满足，响应体不是 json 时不命中
+	JSON []JSONExpr `json:"json"`
+	// 匹配 TLS 证书，非 https 响应没有证书，不会命中
+	Cert *CertRule `json:"cert"`
+	// 关键词和正则关键词的匹配范围，为空时匹配响应体
+	Scope string `json:"scope"`
+	// 关键词、正则关键词、响应头和响应头正则区分大小写，默认不区分
+	CaseSensitive bool        `json:"case_sensitive"`
+	AllOf         []MatchRule `json:"all_of"`  // 子规则需要全部命中
+	AnyOf         []MatchRule `json:"any_of"`  // 子规则命中任意一个即可
+	NoneOf        []MatchRule `json:"none_of"` // 子规则一个都不能命中
+}
+
+// match 判断规则是否命中，命中时返回命中的具体条件
+func (mr *MatchRule) match(resp *Response) ([]Condition, bool) {
+	var conds []Condition
+	headers := resp.HeaderMap()
+	if mr.CaseSensitive {
+		headers = resp.HeaderValues()
+	}
+	// 匹配状态码，指纹规则中有状态码，但是和传进来的不匹配
+	if !mr.StatusCode.IsEmpty() {
+		if !mr.StatusCode.Match(resp.StatusCode) {
+			return nil, false
+		}
+		conds = append(conds, Condition{ConditionStatusCode, strconv.Itoa(resp.StatusCode)})
+	}
+	if !mr.ContentLength.IsEmpty() {
+		if !mr.ContentLength.Match(len(resp.Body)) {
+			return nil, false
+		}
+		conds = append(conds, Condition{ConditionContentLength, strconv.Itoa(len(resp.Body))})
+	}
+	if len(mr.BodyHash) != 0 {
+		hash, ok := resp.matchBodyHash(mr.BodyHash)
+		if !ok {
+			return nil, false
+		}
+		conds = append(conds, Condition{ConditionBodyHash, hash})
+	}
+	// 匹配 header，指纹规则中有请求头，但是没有找到键
+	for _, k := range sortedKeys(mr.Headers) {
+		v := mr.Headers[k]
+		if hk, ok := headers[strings.ToLower(k)]; ok {
+			// *时只匹配键，不区分大小写时 headers 的值已经是小写
+			if v != "*" && !strings.Contains(hk, mr.caseValue(v)) {
+				return nil, false
+			}
+			conds = append(conds, Condition{ConditionHeader, k + ": " + v})
+		} else {
+			return nil, false
+		}
+	}
+	for _, k := range sortedKeys(mr.HeadersRegex) {
+		re := mr.HeadersRegex[k]
+		hk, ok := headers[strings.ToLower(k)]
+		if !ok {
+			return nil, false
+		}
+		loc := re.FindStringIndex(hk)
+		if loc == nil {
+			return nil, false
+		}
+		conds = append(conds, Condition{ConditionHeaderRegex, k + ": " + hk[loc[0]:loc[1]]})
+	}
+	// 匹配标题
+	if len(mr.Title) != 0 || mr.TitleEquals != "" || len(mr.TitleRegex) != 0 {
+		title := strings.TrimSpace(resp.Title)
+		for _, kw := range mr.Title {
+			if !strings.Contains(strings.ToLower(title), strings.ToLower(kw)) {
+				return nil, false
+			}
+			conds = append(conds, Condition{ConditionTitle, kw})
+		}
+		if mr.TitleEquals != "" {
+			if !strings.EqualFold(title, strings.TrimSpace(mr.TitleEquals)) {
+				return nil, false
+			}
+			conds = append(conds, Condition{ConditionTitle, title})
+		}
+		for _, re := range mr.TitleRegex {
+			loc := re.FindStringIndex(title)
+			if loc == nil {
+				return nil, false
+			}
+			conds = append(conds, Condition{ConditionTitleRegex, title[loc[0]:loc[1]]})
+		}
+	}
+	// 匹配 cookie，同名 cookie 有多个时任意一个满足即可
+	for _, k := range sortedKeys(mr.Cookies) {
+		v := mr.Cookies[k]
+		c := resp.findCookie(k, func(value string) bool {
+			return v == "" || v == "*" || value == v
+		})
+		if c == nil {
+			return nil, false
+		}
+		conds = append(conds, Condition{ConditionCookie, c.Name + "=" + c.Value})
+	}
+	for _, k := range sortedKeys(mr.CookiesRegex) {
+		c := resp.findCookie(k, mr.CookiesRegex[k].MatchString)
+		if c == nil {
+			return nil, false
+		}
+		conds = append(conds, Condition{ConditionCookieRegex, c.Name + "=" + c.Value})
+	}
+	if len(mr.Selectors) != 0 {
+		doc := resp.Document()
+		for i := range mr.Selectors {
+			matched, ok := mr.Selectors[i].match(doc)
+			if !ok {
+				return nil, false
+			}
+			conds = append(conds, Condition{ConditionSelector, matched})
+		}
+	}
+	if len(mr.JSON) != 0 {
+		doc, ok := resp.JSON()
+		if !ok {
+			return nil, false
+		}
+		for i := range mr.JSON {
+			matched, ok := mr.JSON[i].match(doc)
+			if !ok {
+				return nil, false
+			}
+			conds = append(conds, Condition{ConditionJSON, matched})
+		}
+	}
+	if mr.Cert != nil {
+		certConds, ok := mr.Cert.match(resp.Certs)
+		if !ok {
+			return nil, false
+		}
+		conds = append(conds, certConds...)
+	}
+	// 匹配正文
+	for _, keyword := range mr.Keyword {
+		if !resp.containsKeyword(keyword, mr.Scope, mr.CaseSensitive) {
+			return nil, false
+		}
+		conds = append(conds, Condition{ConditionKeyword, keyword})
+	}
+	if len(mr.KeywordRegex) != 0 {
+		text := resp.ScopeText(mr.Scope)
+		for _, re := range mr.KeywordRegex {
+			loc := re.FindStringIndex(text)
+			if loc == nil {
+				return nil, false
+			}
+			conds = append(conds, Condition{ConditionKeywordRegex, text[loc[0]:loc[1]]})
+		}
+	}
+	// 嵌套规则
+	for i := range mr.AllOf {
+		subConds, ok := mr.AllOf[i].match(resp)
+		if !ok {
+			return nil, false
+		}
+		conds = append(conds, subConds...)
+	}
+	if len(mr.AnyOf) != 0 {
+		hit := false
+		for i := range mr.AnyOf {
+			if subConds, ok := mr.AnyOf[i].match(resp); ok {
+				conds = append(conds, subConds...)
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return nil, false
+		}
+	}
+	for i := range mr.NoneOf {
+		if _, ok := mr.NoneOf[i].match(resp); ok {
+			return nil, false
+		}
+	}
+	return conds, true
+}
+
+// caseValue 不区分大小写时返回小写的值，用于和小写的响应头比较
+func (mr *MatchRule) caseValue(v string) string {
+	if mr.CaseSensitive {
+		return v
+	}
+	return strings.ToLower(v)
+}
+
+// keywords 收集规则（包括嵌套规则）中所有匹配响应体且不区分大小写的普通关键词
+func (mr *MatchRule) keywords(dst []string) []string {
+	if (mr.Scope == "" || mr.Scope == ScopeBody) && !mr.CaseSensitive {
+		dst = append(dst, mr.Keyword...)
+	}
+	for _, group := range [][]MatchRule{mr.AllOf, mr.AnyOf, mr.NoneOf} {
+		for i := range group {
+			dst = group[i].keywords(dst)
+		}
+	}
+	return dst
+}
+
+// MatchRuleRaw 指纹文件中的匹配条件
+// 顶层的条件直接平铺在指纹中（兼容 FingerprintHub），all_of/any_of/none_of 中可以继续嵌套
+type MatchRuleRaw struct {
+	StatusCode StatusCodeRaw     `json:"status_code"` // 状态码，如 200、[200, "301-302", "4xx", "!404"]
+	Headers    map[string]string `json:"headers"`
+	Keyword    []string          `json:"keyword"`
+	// 响应体的 md5 或 mmh3（和 shodan 的 http.html_hash 一致）
+	BodyHash      []string          `json:"body_hash,omitempty"`
+	ContentLength ContentLengthRaw  `json:"content_length,omitempty"` // 响应体长度，如 1024、[0, "100-200", "4096-"]
+	HeadersRegex  map[string]string `json:"headers_regex"`            // 响应头正则，键为响应头名称，值为正则
+	KeywordRegex  []string          `json:"keyword_regex"`            // 正文正则
+	Title         []string          `json:"title,omitempty"`          // 标题包含的关键词
+	TitleEquals   string            `json:"title_equals,omitempty"`   // 标题完全相等
+	TitleRegex    []string          `json:"title_regex,omitempty"`    // 标题正则
+	Cookies       map[string]string `json:"cookies,omitempty"`        // cookie 名称 -> 值，值为*或者空时只匹配名称
+	CookiesRegex  map[string]string `json:"cookies_regex,omitempty"`  // cookie 名称 -> 值的正则
+	Selectors     []SelectorRuleRaw `json:"selectors,omitempty"`      // CSS 选择器条件
+	JSON          []string          `json:"json,omitempty"`           // json 路径表达式，如 status == "UP"
+	Cert          *CertRule         `json:"cert,omitempty"`           // TLS 证书条件
+	Scope         string            `json:"scope,omitempty"`          // 关键词匹配范围：body（默认）、header、raw、script、comment
+	// 关键词、正则关键词、响应头和响应头正则区分大小写
+	CaseSensitive bool           `json:"case_sensitive,omitempty"`
+	AllOf         []MatchRuleRaw `json:"all_of,omitempty"`
+	AnyOf         []MatchRuleRaw `json:"any_of,omitempty"`
+	NoneOf        []MatchRuleRaw `json:"none_of,omitempty"`
+}
+
+// toMatchRule 转换为匹配规则，正则在此时统一编译，运行时不再重复编译
+func (mrr *MatchRuleRaw) toMatchRule() (mr MatchRule, err error) {
+	mr = MatchRule{
+		Title:         mrr.Title,
+		TitleEquals:   mrr.TitleEquals,
+		Keyword:       mrr.Keyword,
+		BodyHash:      mrr.BodyHash,
+		Headers:       lowerMap(mrr.Headers),
+		Scope:         strings.ToLower(mrr.Scope),
+		CaseSensitive: mrr.CaseSensitive,
+	}
+	if mrr.CaseSensitive {
+		// 区分大小写时只把响应头名称转为小写
+		mr.Headers = make(map[string]string, len(mrr.Headers))
+		for k, v := range mrr.Headers {
+			mr.Headers[strings.ToLower(k)] = v
+		}
+	}
+	if mr.StatusCode, err = ParseStatusCodes(mrr.StatusCode...); err != nil {
+		return mr, err
+	}
+	if mr.ContentLength, err = ParseContentLengths(mrr.ContentLength...); err != nil {
+		return mr, err
+	}
+	if !slices.Contains(scopes, mr.Scope) {
+		return mr, fmt.Errorf("不支持的关键词匹配范围 %s", mrr.Scope)
+	}
+	if len(mrr.HeadersRegex) != 0 {
+		mr.HeadersRegex = make(map[string]*regexp.Regexp, len(mrr.HeadersRegex))
+		for k, expr := range mrr.HeadersRegex {
+			re, err := compileRegexCase(expr, mrr.CaseSensitive)
+			if err != nil {
+				return mr, fmt.Errorf("响应头 %s 正则不合法: %w", k, err)
+			}
+			mr.HeadersRegex[strings.ToLower(k)] = re
+		}
+	}
+	for _, expr := range mrr.TitleRegex {
+		re, err := compileRegex(expr)
+		if err != nil {
+			return mr, fmt.Errorf("标题正则不合法: %w", err)
+		}
+		mr.TitleRegex = append(mr.TitleRegex, re)
+	}
+	if len(mrr.Cookies) != 0 {
+		mr.Cookies = make(map[string]string, len(mrr.Cookies))
+		for k, v := range mrr.Cookies {
+			mr.Cookies[strings.ToLower(k)] = v
+		}
+	}
+	if len(mrr.CookiesRegex) != 0 {
+		mr.CookiesRegex = make(map[string]*regexp.Regexp, len(mrr.CookiesRegex))
+		for k, expr := range mrr.CookiesRegex {
+			re, err := compileRegex(expr)
+			if err != nil {
+				return mr, fmt.Errorf("cookie %s 正则不合法: %w", k, err)
+			}
+			mr.CookiesRegex[strings.ToLower(k)] = re
+		}
+	}
+	for i := range mrr.Selectors {
+		sr, err := mrr.Selectors[i].toSelectorRule()
+		if err != nil {
+			return mr, err
+		}
+		mr.Selectors = append(mr.Selectors, sr)
+	}
+	if mrr.Cert != nil && !mrr.Cert.isEmpty() {
+		cert := *mrr.Cert
+		mr.Cert = &cert
+	}
+	for _, expr := range mrr.JSON {
+		je, err := ParseJSONExpr(expr)
+		if err != nil {
+			return mr, err
+		}
+		mr.JSON = append(mr.JSON, je)
+	}
+	for _, expr := range mrr.KeywordRegex {
+		re, err := compileRegexCase(expr, mrr.CaseSensitive)
+		if err != nil {
+			return mr, fmt.Errorf("关键词正则不合法: %w", err)
+		}
+		mr.KeywordRegex = append(mr.KeywordRegex, re)
+	}
+	groups := []struct {
+		raws  []MatchRuleRaw
+		rules *[]MatchRule
+		name  string
+	}{
+		{mrr.AllOf, &mr.AllOf, "all_of"},
+		{mrr.AnyOf, &mr.AnyOf, "any_of"},
+		{mrr.NoneOf, &mr.NoneOf, "none_of"},
+	}
+	for _, g := range groups {
+		for i := range g.raws {
+			sub, err := g.raws[i].toMatchRule()
+			if err != nil {
+				return mr, fmt.Errorf("%s[%d] 中%w", g.name, i, err)
+			}
+			*g.rules = append(*g.rules, sub)
+		}
+	}
+	return mr, nil
+}
@@ -0,0 +1,177 @@
+package finger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/akkuman/webeye/utils"
+)
+
+// RuleTests 指纹规则的自测用例，值为 fixture 文件路径
+type RuleTests struct {
+	Match    []string `json:"match"`     // 必须命中的 fixture
+	NotMatch []string `json:"not_match"` // 必须不命中的 fixture
+}
+
+// Fixture 离线的响应数据，用于在没有真实目标的情况下测试指纹
+// 文件中的相对路径都相对于 fixture 文件所在的目录
+type Fixture struct {
+	URL            string                `json:"url"`
+	StatusCode     int                   `json:"status_code"`
+	Headers        map[string]stringList `json:"headers"` // 值可以是字符串或字符串列表
+	Body           string                `json:"body"`
+	BodyFile       string                `json:"body_file"` // 响应体文件，优先于 body
+	Title          string                `json:"title"`     // 为空时使用 RuleTester.TitleFunc 从响应体提取
+	FaviconHashes  []string              `json:"favicon_hashes"`
+	FaviconFiles   []string              `json:"favicon_files"` // 图标文件，会计算 md5、mmh3 和感知哈希
+	FaviconPHashes []string              `json:"favicon_phashes"`
+}
+
+// LoadFixture 读取 fixture 文件并转为指纹匹配使用的响应数据
+func LoadFixture(path string, titleFunc func(body []byte) string) (*Response, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(content, &fixture); err != nil {
+		return nil, fmt.Errorf("解析 fixture %s 失败: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	resp := &Response{
+		URL:            fixture.URL,
+		StatusCode:     fixture.StatusCode,
+		Header:         make(http.Header),
+		Body:           []byte(fixture.Body),
+		Title:          fixture.Title,
+		Favicons:       fixture.FaviconHashes,
+		FaviconPHashes: fixture.FaviconPHashes,
+	}
+	if resp.StatusCode == 0 {
+		resp.StatusCode = http.StatusOK
+	}
+	for k, values := range fixture.Headers {
+		for _, v := range values {
+			resp.Header.Add(k, v)
+		}
+	}
+	if fixture.BodyFile != "" {
+		if resp.Body, err = os.ReadFile(filepath.Join(dir, fixture.BodyFile)); err != nil {
+			return nil, err
+		}
+	}
+	if resp.Title == "" && titleFunc != nil {
+		resp.Title = titleFunc(resp.Body)
+	}
+	for _, f := range fixture.FaviconFiles {
+		data, err := os.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			return nil, err
+		}
+		resp.Favicons = append(resp.Favicons, utils.MD5Hex(data), utils.ShodanHash(data))
+		if phash := utils.PerceptualHash(data); phash != "" {
+			resp.FaviconPHashes = append(resp.FaviconPHashes, phash)
+		}
+	}
+	return resp, nil
+}
+
+// RuleTestFailure 一个未通过的用例
+type RuleTestFailure struct {
+	Fixture   string // fixture 文件路径
+	WantMatch bool   // 期望命中还是不命中
+	Error     error  // fixture 加载失败时的错误
+}
+
+func (f RuleTestFailure) String() string {
+	if f.Error != nil {
+		return fmt.Sprintf("%s: %v", f.Fixture, f.Error)
+	}
+	if f.WantMatch {
+		return fmt.Sprintf("%s: 期望命中，实际未命中", f.Fixture)
+	}
+	return fmt.Sprintf("%s: 期望不命中，实际命中", f.Fixture)
+}
+
+// RuleTestResult 单条指纹规则的测试结果
+type RuleTestResult struct {
+	Name     string
+	Kind     string // 指纹的命中方式：index、favicon、custom
+	Total    int    // 用例数量
+	Failures []RuleTestFailure
+}
+
+// RuleTester 使用 fixture 离线测试指纹规则
+type RuleTester struct {
+	BaseDir   string                   // fixture 相对路径的基准目录，一般为指纹文件所在目录
+	TitleFunc func(body []byte) string // fixture 没有 title 时用于提取标题，如 req.ExtractTitle
+
+	fixtures map[string]*Response
+}
+
+func (rt *RuleTester) loadFixture(path string) (*Response, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(rt.BaseDir, path)
+	}
+	if resp, ok := rt.fixtures[path]; ok {
+		return resp, nil
+	}
+	resp, err := LoadFixture(path, rt.TitleFunc)
+	if err != nil {
+		return nil, err
+	}
+	if rt.fixtures == nil {
+		rt.fixtures = make(map[string]*Response)
+	}
+	rt.fixtures[path] = resp
+	return resp, nil
+}
+
+// TestFinger 使用规则自带的用例测试单条指纹，图标指纹匹配图标 hash 和感知哈希，其余指纹使用 MatchKeyWordResponse
+func (rt *RuleTester) TestFinger(wf *WebFinger) RuleTestResult {
+	res := RuleTestResult{Name: wf.Name, Kind: wf.MatchKind()}
+	cases := []struct {
+		fixtures  []string
+		wantMatch bool
+	}{
+		{wf.Tests.Match, true},
+		{wf.Tests.NotMatch, false},
+	}
+	for _, c := range cases {
+		for _, fixture := range c.fixtures {
+			res.Total++
+			resp, err := rt.loadFixture(fixture)
+			if err != nil {
+				res.Failures = append(res.Failures, RuleTestFailure{fixture, c.wantMatch, err})
+				continue
+			}
+			var matched bool
+			if wf.IsFavicon() {
+				matched = len(wf.matchFavicons(resp)) > 0
+			} else {
+				matched = wf.MatchKeyWordResponse(resp)
+			}
+			if matched != c.wantMatch {
+				res.Failures = append(res.Failures, RuleTestFailure{fixture, c.wantMatch, nil})
+			}
+		}
+	}
+	return res
+}
+
+// Run 测试所有带用例的指纹，返回每条指纹的测试结果
+func (rt *RuleTester) Run(wfs *WebFingerSystem) []RuleTestResult {
+	var results []RuleTestResult
+	for _, fingers := range [][]WebFinger{wfs.Indexs, wfs.CustomReqs, wfs.Favicons} {
+		for i := range fingers {
+			if len(fingers[i].Tests.Match)+len(fingers[i].Tests.NotMatch) == 0 {
+				continue
+			}
+			results = append(results, rt.TestFinger(&fingers[i]))
+		}
+	}
+	return results
+}
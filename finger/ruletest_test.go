@@ -0,0 +1,81 @@
+package finger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/akkuman/webeye/utils"
+	"github.com/go-test/deep"
+)
+
+func TestRuleTester(t *testing.T) {
+	dir := t.TempDir()
+	icon := []byte("fake icon")
+	files := map[string]string{
+		"nginx.json":     `{"headers": {"Server": "nginx/1.20"}, "body": "<html></html>"}`,
+		"tomcat.json":    `{"status_code": 404, "body_file": "tomcat.html"}`,
+		"tomcat.html":    `<html><head><title>Apache Tomcat/9.0</title></head></html>`,
+		"icon.json":      `{"favicon_files": ["favicon.ico"]}`,
+		"favicon.ico":    string(icon),
+		"malformed.json": `{`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wfs, err := ParseWebFinger(`[
+		{"path": "/", "request_method": "get", "headers": {"Server": "nginx"}, "name": "nginx",
+		 "tests": {"match": ["nginx.json"], "not_match": ["tomcat.json"]}},
+		{"path": "/", "request_method": "get", "keyword": ["<title>Apache Tomcat"], "name": "tomcat",
+		 "tests": {"match": ["tomcat.json", "nginx.json", "malformed.json"]}},
+		{"path": "/", "request_method": "get", "favicon_hash": ["` + utils.ShodanHash(icon) + `"], "name": "icon",
+		 "tests": {"match": ["icon.json"], "not_match": ["nginx.json"]}},
+		{"path": "/", "request_method": "get", "keyword": ["untested"], "name": "untested"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tester := &RuleTester{BaseDir: dir}
+	type result struct {
+		Name     string
+		Total    int
+		Failures []string
+	}
+	var got []result
+	for _, res := range tester.Run(wfs) {
+		r := result{Name: res.Name, Total: res.Total}
+		for _, f := range res.Failures {
+			r.Failures = append(r.Failures, filepath.Base(f.Fixture))
+		}
+		got = append(got, r)
+	}
+	want := []result{
+		{Name: "nginx", Total: 2},
+		{Name: "tomcat", Total: 3, Failures: []string{"nginx.json", "malformed.json"}},
+		{Name: "icon", Total: 2},
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestLoadFixture(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fixture.json")
+	content := `{"url": "http://example.com/", "headers": {"Set-Cookie": ["a=1", "b=2"]}, "body": "<title>x</title>", "favicon_hashes": ["123"]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := LoadFixture(path, func(body []byte) string { return "title:" + string(body) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(
+		[]any{resp.URL, resp.StatusCode, resp.Header.Values("Set-Cookie"), resp.Title, resp.Favicons},
+		[]any{"http://example.com/", 200, []string{"a=1", "b=2"}, "title:<title>x</title>", []string{"123"}},
+	); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,84 @@
+package finger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// SelectorRule 使用 CSS 选择器匹配 HTML 文档中的元素
+// 没有 value 和 regex 时只要求存在满足选择器的元素（指定 attr 时还需要存在该属性）
+// 否则要求任意一个元素的属性值（指定 attr 时）或文本满足 value 和 regex
+type SelectorRule struct {
+	Selector string         // CSS 选择器，如 meta[name=generator]
+	Attr     string         // 匹配的属性名称，为空时匹配元素文本
+	Value    string         // 属性值或文本需要包含的值，不区分大小写
+	Regex    *regexp.Regexp // 属性值或文本需要匹配的正则，默认不区分大小写
+	matcher  cascadia.Selector
+}
+
+// match 返回第一个满足条件的元素描述，如 meta[name=generator]@content: WordPress 6.2
+func (sr *SelectorRule) match(doc *goquery.Document) (string, bool) {
+	if doc == nil {
+		return "", false
+	}
+	var matched string
+	found := false
+	doc.FindMatcher(sr.matcher).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		var target string
+		if sr.Attr != "" {
+			v, ok := s.Attr(sr.Attr)
+			if !ok {
+				return true
+			}
+			target = v
+		} else {
+			target = strings.TrimSpace(s.Text())
+		}
+		if sr.Value != "" && !strings.Contains(strings.ToLower(target), strings.ToLower(sr.Value)) {
+			return true
+		}
+		if sr.Regex != nil {
+			loc := sr.Regex.FindStringIndex(target)
+			if loc == nil {
+				return true
+			}
+			target = target[loc[0]:loc[1]]
+		}
+		matched, found = sr.String()+": "+target, true
+		return false
+	})
+	return matched, found
+}
+
+// String 返回选择器描述，指定属性时格式为 selector@attr
+func (sr *SelectorRule) String() string {
+	if sr.Attr == "" {
+		return sr.Selector
+	}
+	return sr.Selector + "@" + sr.Attr
+}
+
+// SelectorRuleRaw 指纹文件中的选择器条件
+type SelectorRuleRaw struct {
+	Selector string `json:"selector"`
+	Attr     string `json:"attr,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Regex    string `json:"regex,omitempty"`
+}
+
+func (srr *SelectorRuleRaw) toSelectorRule() (sr SelectorRule, err error) {
+	sr = SelectorRule{Selector: srr.Selector, Attr: strings.ToLower(srr.Attr), Value: srr.Value}
+	if sr.matcher, err = cascadia.Compile(srr.Selector); err != nil {
+		return sr, fmt.Errorf("选择器 %s 不合法: %w", srr.Selector, err)
+	}
+	if srr.Regex != "" {
+		if sr.Regex, err = compileRegex(srr.Regex); err != nil {
+			return sr, fmt.Errorf("选择器 %s 的正则不合法: %w", srr.Selector, err)
+		}
+	}
+	return sr, nil
+}
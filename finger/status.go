@@ -0,0 +1,153 @@
+package finger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// statusRange 闭区间的状态码范围
+type statusRange struct {
+	min, max int
+}
+
+func (sr statusRange) contains(code int) bool {
+	return code >= sr.min && code <= sr.max
+}
+
+// StatusCodes 状态码条件，为空时匹配任意状态码
+// 需要满足任意一个 include（没有 include 时视为满足），并且不能满足任何一个 exclude
+type StatusCodes struct {
+	include []statusRange
+	exclude []statusRange
+	raw     []string
+}
+
+// ParseStatusCodes 解析状态码条件，每一项可以是 200、301-302、4xx、40x，前面加 ! 表示排除，如 !404
+func ParseStatusCodes(values ...string) (sc StatusCodes, err error) {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		negative := strings.HasPrefix(v, "!")
+		sr, err := parseStatusRange(strings.TrimSpace(strings.TrimPrefix(v, "!")))
+		if err != nil {
+			return sc, err
+		}
+		if negative {
+			sc.exclude = append(sc.exclude, sr)
+		} else {
+			sc.include = append(sc.include, sr)
+		}
+		sc.raw = append(sc.raw, v)
+	}
+	return sc, nil
+}
+
+func parseStatusRange(s string) (sr statusRange, err error) {
+	if lo, hi, ok := strings.Cut(s, "-"); ok {
+		if sr.min, err = parseStatusCode(lo); err != nil {
+			return sr, err
+		}
+		if sr.max, err = parseStatusCode(hi); err != nil {
+			return sr, err
+		}
+		if sr.min > sr.max {
+			return sr, fmt.Errorf("状态码范围 %s 不合法", s)
+		}
+		return sr, nil
+	}
+	// 4xx、40x 这类写法，x 代表任意数字
+	if len(s) == 3 && strings.ContainsAny(s, "xX") {
+		lower := strings.ToLower(s)
+		if sr.min, err = parseStatusCode(strings.ReplaceAll(lower, "x", "0")); err != nil {
+			return sr, fmt.Errorf("状态码 %s 不合法", s)
+		}
+		if sr.max, err = parseStatusCode(strings.ReplaceAll(lower, "x", "9")); err != nil {
+			return sr, fmt.Errorf("状态码 %s 不合法", s)
+		}
+		return sr, nil
+	}
+	code, err := parseStatusCode(s)
+	return statusRange{code, code}, err
+}
+
+func parseStatusCode(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || code < 100 || code > 999 {
+		return 0, fmt.Errorf("状态码 %s 不合法", s)
+	}
+	return code, nil
+}
+
+// IsEmpty 判断是否没有任何状态码条件
+func (sc StatusCodes) IsEmpty() bool {
+	return len(sc.include) == 0 && len(sc.exclude) == 0
+}
+
+// Match 判断状态码是否满足条件
+func (sc StatusCodes) Match(code int) bool {
+	for _, sr := range sc.exclude {
+		if sr.contains(code) {
+			return false
+		}
+	}
+	if len(sc.include) == 0 {
+		return true
+	}
+	for _, sr := range sc.include {
+		if sr.contains(code) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sc StatusCodes) String() string {
+	return strings.Join(sc.raw, ",")
+}
+
+// StatusCodeRaw 指纹文件中的状态码条件，兼容 FingerprintHub 的单个数字（0 代表任意状态码）
+// 也可以是字符串或数字与字符串混合的列表，如 [200, "301-302", "4xx", "!404"]
+type StatusCodeRaw []string
+
+func (scr *StatusCodeRaw) UnmarshalJSON(data []byte) error {
+	items, err := unmarshalIntStringList(data)
+	if err != nil {
+		return fmt.Errorf("状态码%w", err)
+	}
+	*scr = nil
+	for _, item := range items {
+		if item != "0" {
+			*scr = append(*scr, item)
+		}
+	}
+	return nil
+}
+
+// unmarshalIntStringList 解析单个整数、字符串或两者混合的列表，整数转为字符串，null 会被忽略
+func unmarshalIntStringList(data []byte) ([]string, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	items, ok := v.([]any)
+	if !ok {
+		items = []any{v}
+	}
+	var list []string
+	for _, item := range items {
+		switch t := item.(type) {
+		case nil:
+		case float64:
+			if t != float64(int(t)) {
+				return nil, fmt.Errorf(" %v 不是整数", t)
+			}
+			list = append(list, strconv.Itoa(int(t)))
+		case string:
+			list = append(list, t)
+		default:
+			return nil, fmt.Errorf(" %s 不合法", data)
+		}
+	}
+	return list, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -20,6 +21,29 @@ func lowerMap(m map[string]string) map[string]string {
 	return newMap
 }
 
+// sortedKeys 返回排序后的 map 键，保证遍历顺序稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compileRegex 编译指纹中的正则，和普通关键词保持一致，默认不区分大小写
+func compileRegex(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + expr)
+}
+
+// compileRegexCase 编译指纹中的正则，caseSensitive 为 true 时区分大小写
+func compileRegexCase(expr string, caseSensitive bool) (*regexp.Regexp, error) {
+	if caseSensitive {
+		return regexp.Compile(expr)
+	}
+	return compileRegex(expr)
+}
+
 // CheckRootPath 检查 root_path 是否符合规范
 func CheckRootPath(rootPath string) error {
 	if !urlPathPattern.MatchString(rootPath) {
@@ -37,4 +61,4 @@ func HTTPHeadersToMap(headers http.Header) map[string]string {
 		headerMap[strings.ToLower(hk)] = strings.ToLower(strings.Join(hv, "; "))
 	}
 	return headerMap
-}
\ No newline at end of file
+}
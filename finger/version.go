@@ -0,0 +1,131 @@
+package finger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 版本提取的数据来源
+const (
+	VersionFromBody   = "body"
+	VersionFromHeader = "header"
+	VersionFromTitle  = "title"
+	VersionFromCookie = "cookie"
+	VersionFromJSON   = "json"
+	VersionFromRaw    = ScopeRaw // 完整响应，包括状态行、响应头和响应体
+)
+
+// VersionExtractor 版本提取器，使用正则的捕获组从响应中提取版本号
+type VersionExtractor struct {
+	From  string         `json:"from"`  // 数据来源：body、header、title、cookie、json、raw
+	Name  string         `json:"name"`  // from 为 header 时的响应头名称，为空时从所有响应头中提取；from 为 cookie 时的 cookie 名称；from 为 json 时的路径
+	Regex *regexp.Regexp `json:"regex"` // 提取正则
+	Group int            `json:"group"` // 版本号所在的捕获组
+	path  JSONExpr       // from 为 json 时解析后的路径
+}
+
+// Extract 从响应中提取版本号，未提取到时返回空字符串
+func (ve *VersionExtractor) Extract(resp *Response) string {
+	var text string
+	switch ve.From {
+	case VersionFromHeader:
+		if ve.Name == "" {
+			text = resp.HeaderText()
+		} else {
+			text = strings.Join(resp.Header.Values(ve.Name), "; ")
+		}
+	case VersionFromTitle:
+		text = resp.Title
+	case VersionFromRaw:
+		text = resp.ScopeText(ScopeRaw)
+	case VersionFromCookie:
+		var values []string
+		for _, c := range resp.Cookies() {
+			if strings.EqualFold(c.Name, ve.Name) {
+				values = append(values, c.Value)
+			}
+		}
+		text = strings.Join(values, "; ")
+	case VersionFromJSON:
+		doc, ok := resp.JSON()
+		if !ok {
+			return ""
+		}
+		if text, ok = ve.path.Lookup(doc); !ok {
+			return ""
+		}
+	default:
+		text = resp.ScopeText(ScopeBody)
+	}
+	m := ve.Regex.FindStringSubmatch(text)
+	if ve.Group >= len(m) {
+		return ""
+	}
+	return strings.TrimSpace(m[ve.Group])
+}
+
+// VersionExtractorRaw 指纹文件中的版本提取器
+type VersionExtractorRaw struct {
+	From  string `json:"from"`  // 数据来源：body（默认）、header、title、cookie、json、raw
+	Name  string `json:"name"`  // from 为 header 时的响应头名称，from 为 cookie 时的 cookie 名称，from 为 json 时的路径，如 version.number
+	Regex string `json:"regex"` // 提取正则，from 为 json 时可以为空，直接使用路径对应的值
+	// 版本号所在的捕获组，为 0 时优先使用名为 version 的捕获组，其次是第一个捕获组
+	Group int `json:"group"`
+}
+
+func (ver *VersionExtractorRaw) toVersionExtractor() (ve VersionExtractor, err error) {
+	from := strings.ToLower(ver.From)
+	if from == VersionFromHeader && ver.Name == "" {
+		return ve, fmt.Errorf("从响应头提取版本时必须指定 name")
+	}
+	if from == VersionFromCookie && ver.Name == "" {
+		return ve, fmt.Errorf("从 cookie 提取版本时必须指定 name")
+	}
+	if from == VersionFromJSON && ver.Name == "" {
+		return ve, fmt.Errorf("从 json 提取版本时必须指定 name 为路径")
+	}
+	return newVersionExtractor(from, ver.Name, ver.Regex, ver.Group)
+}
+
+// newVersionExtractor 编译版本提取器，group 为 0 时优先使用名为 version 的捕获组，其次是第一个捕获组
+func newVersionExtractor(from string, name string, expr string, group int) (ve VersionExtractor, err error) {
+	ve.From = from
+	if ve.From == "" {
+		ve.From = VersionFromBody
+	}
+	switch ve.From {
+	case VersionFromBody, VersionFromTitle, VersionFromRaw:
+	case VersionFromHeader, VersionFromCookie:
+		ve.Name = name
+	case VersionFromJSON:
+		ve.Name = name
+		if ve.path, err = ParseJSONExpr(name); err != nil {
+			return ve, err
+		}
+		if ve.path.Op != JSONOpExists {
+			return ve, fmt.Errorf("提取版本的 json 路径 %s 不能包含运算符", name)
+		}
+		if expr == "" {
+			expr = `(.+)`
+		}
+	default:
+		return ve, fmt.Errorf("不支持的版本来源 %s", from)
+	}
+	ve.Regex, err = compileRegex(expr)
+	if err != nil {
+		return ve, fmt.Errorf("版本提取正则不合法: %w", err)
+	}
+	ve.Group = group
+	if ve.Group == 0 {
+		if i := ve.Regex.SubexpIndex("version"); i > 0 {
+			ve.Group = i
+		} else if ve.Regex.NumSubexp() > 0 {
+			ve.Group = 1
+		}
+	}
+	if ve.Group > ve.Regex.NumSubexp() {
+		return ve, fmt.Errorf("版本提取正则 %s 不存在捕获组 %d", expr, ve.Group)
+	}
+	return ve, nil
+}
@@ -0,0 +1,239 @@
+package finger
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// stringList 兼容字符串和字符串列表两种写法
+type stringList []string
+
+func (sl *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*sl = stringList{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(data, &l); err != nil {
+		return err
+	}
+	*sl = l
+	return nil
+}
+
+// WappalyzerTechnology Wappalyzer 格式的技术定义，只包含可以通过 http 响应识别的字段
+type WappalyzerTechnology struct {
+	Cats      []int                 `json:"cats"`
+	Headers   map[string]string     `json:"headers"`
+	Cookies   map[string]string     `json:"cookies"`
+	Meta      map[string]stringList `json:"meta"`
+	ScriptSrc stringList            `json:"scriptSrc"`
+	HTML      stringList            `json:"html"`
+	Implies   stringList            `json:"implies"`
+	CPE       string                `json:"cpe"`
+}
+
+// WappalyzerCategory Wappalyzer 的分类定义，位于 categories.json 或旧版 apps.json 的 categories 字段
+type WappalyzerCategory struct {
+	Name string `json:"name"`
+}
+
+// wappalyzerPattern Wappalyzer 中带标签的正则，如 nginx(?:/([\d.]+))?\;version:\1
+type wappalyzerPattern struct {
+	Regex   string
+	Version int // 版本号所在的捕获组，0 代表没有版本
+}
+
+var reWappalyzerVersion = regexp.MustCompile(`^\\(\d+)$`)
+
+func parseWappalyzerPattern(s string) wappalyzerPattern {
+	parts := strings.Split(s, `\;`)
+	p := wappalyzerPattern{Regex: parts[0]}
+	for _, tag := range parts[1:] {
+		k, v, ok := strings.Cut(tag, ":")
+		if !ok || k != "version" {
+			continue
+		}
+		// 只支持直接引用捕获组的版本号，三元表达式等写法忽略
+		if m := reWappalyzerVersion.FindStringSubmatch(v); m != nil {
+			p.Version, _ = strconv.Atoi(m[1])
+		}
+	}
+	return p
+}
+
+// wappalyzerRuleBuilder 把 Wappalyzer 的各类正则转为 any_of 中的子规则
+type wappalyzerRuleBuilder struct {
+	name     string
+	anyOf    []MatchRule
+	versions []VersionExtractor
+	warnings []string // 被跳过的正则及原因
+}
+
+// skip 记录一条被跳过的正则
+func (b *wappalyzerRuleBuilder) skip(pattern wappalyzerPattern, err error) {
+	b.warnings = append(b.warnings, fmt.Sprintf("跳过 Wappalyzer 技术 %s 的正则 %s: %v", b.name, pattern.Regex, err))
+}
+
+// add 添加一条正则子规则，header 为 from 是 header 或 cookie 时的名称，Wappalyzer 使用 JavaScript 正则，RE2 不支持的写法（如断言）会被跳过
+func (b *wappalyzerRuleBuilder) add(pattern wappalyzerPattern, regex string, from string, header string) {
+	re, err := compileRegex(regex)
+	if err != nil {
+		b.skip(pattern, err)
+		return
+	}
+	mr := MatchRule{}
+	switch from {
+	case VersionFromHeader:
+		mr.HeadersRegex = map[string]*regexp.Regexp{header: re}
+	case VersionFromCookie:
+		mr.CookiesRegex = map[string]*regexp.Regexp{header: re}
+	default:
+		mr.KeywordRegex = []*regexp.Regexp{re}
+	}
+	b.anyOf = append(b.anyOf, mr)
+	if pattern.Version > 0 && pattern.Version <= re.NumSubexp() {
+		b.versions = append(b.versions, VersionExtractor{From: from, Name: header, Regex: re, Group: pattern.Version})
+	}
+}
+
+// addMeta 使用选择器匹配 meta 标签的 content，name 和 property 都可以作为 meta 名称
+// 版本号依然使用响应体正则提取
+func (b *wappalyzerRuleBuilder) addMeta(name string, pattern wappalyzerPattern) {
+	srr := SelectorRuleRaw{
+		Selector: fmt.Sprintf(`meta[name=%q i], meta[property=%q i]`, name, name),
+		Attr:     "content",
+		Regex:    pattern.Regex,
+	}
+	sr, err := srr.toSelectorRule()
+	if err != nil {
+		b.skip(pattern, err)
+		return
+	}
+	b.anyOf = append(b.anyOf, MatchRule{Selectors: []SelectorRule{sr}})
+	if pattern.Version == 0 {
+		return
+	}
+	content := `[^"'>]*?(?:` + strings.TrimPrefix(pattern.Regex, "^") + `)`
+	re, err := compileRegex(`<meta[^>]+(?:name|property)=["']?` + regexp.QuoteMeta(name) + `["']?[^>]+content=["']?` + content)
+	if err == nil && pattern.Version <= re.NumSubexp() {
+		b.versions = append(b.versions, VersionExtractor{From: VersionFromBody, Regex: re, Group: pattern.Version})
+	}
+}
+
+// metadata 转换 cpe 和 cats，第一个分类作为指纹分类，所有分类名称同时作为标签
+// 没有分类定义时只能得到分类 id，此时忽略分类
+func (wt *WappalyzerTechnology) metadata(categories map[string]WappalyzerCategory) Metadata {
+	m := Metadata{CPE: wt.CPE}
+	for _, id := range wt.Cats {
+		cat, ok := categories[strconv.Itoa(id)]
+		if !ok || cat.Name == "" {
+			continue
+		}
+		if m.Category == "" {
+			m.Category = cat.Name
+		}
+		m.Tags = append(m.Tags, cat.Name)
+	}
+	// CPE 不合法时只丢弃 CPE，不影响指纹本身
+	if m.normalize() != nil {
+		m.CPE = ""
+	}
+	return m
+}
+
+// toWebFinger 转换为首页指纹，没有任何可用规则时 MatchRules 为空，warnings 为被跳过的正则及原因
+func (wt *WappalyzerTechnology) toWebFinger(name string, categories map[string]WappalyzerCategory) (*WebFinger, []string) {
+	b := &wappalyzerRuleBuilder{name: name}
+	for _, k := range sortedKeys(wt.Headers) {
+		header := strings.ToLower(k)
+		p := parseWappalyzerPattern(wt.Headers[k])
+		if p.Regex == "" {
+			b.anyOf = append(b.anyOf, MatchRule{Headers: map[string]string{header: "*"}})
+			continue
+		}
+		b.add(p, p.Regex, VersionFromHeader, header)
+	}
+	for _, k := range sortedKeys(wt.Cookies) {
+		p := parseWappalyzerPattern(wt.Cookies[k])
+		if p.Regex == "" {
+			b.anyOf = append(b.anyOf, MatchRule{Cookies: map[string]string{strings.ToLower(k): "*"}})
+			continue
+		}
+		b.add(p, p.Regex, VersionFromCookie, strings.ToLower(k))
+	}
+	for _, k := range sortedKeys(wt.Meta) {
+		for _, v := range wt.Meta[k] {
+			b.addMeta(k, parseWappalyzerPattern(v))
+		}
+	}
+	for _, v := range wt.ScriptSrc {
+		p := parseWappalyzerPattern(v)
+		regex := `<script[^>]+src=["']?[^"'>]*?(?:` + strings.TrimPrefix(p.Regex, "^") + `)`
+		b.add(p, regex, VersionFromBody, "")
+	}
+	for _, v := range wt.HTML {
+		p := parseWappalyzerPattern(v)
+		b.add(p, p.Regex, VersionFromBody, "")
+	}
+	var implies []string
+	for _, v := range wt.Implies {
+		implies = append(implies, parseWappalyzerPattern(v).Regex)
+	}
+	return &WebFinger{
+		Metadata:   wt.metadata(categories),
+		Name:       name,
+		Request:    RequestInfo{Path: "/", RequestMethod: "get"},
+		MatchRules: MatchRule{AnyOf: b.anyOf},
+		RootPath:   "/",
+		Version:    b.versions,
+		Implies:    implies,
+	}, b.warnings
+}
+
+// ParseWappalyzer 解析 Wappalyzer 格式的技术定义
+// 支持完整的 technologies.json（包含 technologies 或 apps 字段），也支持 src/technologies 下按字母拆分的文件
+// 只转换 headers、cookies、meta、scriptSrc、html、implies 和 cpe，所有技术都作为首页指纹
+// 文件中包含 categories 字段时，cats 会转换为指纹的分类和标签
+// RE2 不支持的正则会被跳过并记录在 Warnings 中
+// 没有可用规则的技术（如只能通过 js、dom 识别的技术）不会生成指纹，而是放入 Relations，依然可以通过 implies 被推导出来并继续推导其他技术
+func ParseWappalyzer(content string) (*WebFingerSystem, error) {
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &file); err != nil {
+		return nil, err
+	}
+	techData := []byte(content)
+	for _, k := range []string{"technologies", "apps"} {
+		if v, ok := file[k]; ok {
+			techData = v
+			break
+		}
+	}
+	techs := make(map[string]WappalyzerTechnology)
+	if err := json.Unmarshal(techData, &techs); err != nil {
+		return nil, fmt.Errorf("解析 Wappalyzer 技术定义失败: %w", err)
+	}
+	categories := make(map[string]WappalyzerCategory)
+	if v, ok := file["categories"]; ok {
+		if err := json.Unmarshal(v, &categories); err != nil {
+			return nil, fmt.Errorf("解析 Wappalyzer 分类定义失败: %w", err)
+		}
+	}
+	wfs := new(WebFingerSystem)
+	for _, name := range sortedKeys(techs) {
+		tech := techs[name]
+		wf, warnings := tech.toWebFinger(name, categories)
+		wfs.Warnings = append(wfs.Warnings, warnings...)
+		if len(wf.MatchRules.AnyOf) == 0 {
+			wfs.Relations = append(wfs.Relations, *wf)
+			continue
+		}
+		wfs.Indexs = append(wfs.Indexs, *wf)
+	}
+	wfs.Build()
+	return wfs, nil
+}
@@ -0,0 +1,81 @@
+package finger
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestParseWappalyzer(t *testing.T) {
+	wfs, err := ParseWappalyzer(`{
+		"technologies": {
+			"Nginx": {
+				"cats": [22],
+				"headers": {"Server": "nginx(?:/([\\d.]+))?\\;version:\\1"},
+				"implies": "C"
+			},
+			"WordPress": {
+				"cats": [1],
+				"meta": {"generator": "^WordPress ?([\\d.]+)?\\;version:\\1"},
+				"scriptSrc": ["/wp-(?:content|includes)/"],
+				"html": "<link rel=[\"']stylesheet[\"'] [^>]+/wp-(?:content|includes)/",
+				"implies": ["PHP", "MySQL\\;confidence:50"]
+			},
+			"PHP": {
+				"cookies": {"PHPSESSID": ""},
+				"headers": {"X-Powered-By": "^php/?([\\d.]+)?\\;version:\\1"}
+			},
+			"MySQL": {"cats": [34]},
+			"Lookahead": {"html": "(?!unsupported)"},
+			"Nuxt.js": {"html": "<div id=\"__nuxt\">", "implies": "Vue.js"},
+			"Vue.js": {"js": {"Vue.version": "^(.+)$\\;version:\\1"}, "implies": "JavaScript"},
+			"JavaScript": {}
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wfs.Count() != 4 {
+		t.Errorf("wfs.Count() = %d; want 4", wfs.Count())
+	}
+	if len(wfs.Warnings) != 1 || !strings.Contains(wfs.Warnings[0], "Lookahead") {
+		t.Errorf("Warnings = %v; want Lookahead skipped", wfs.Warnings)
+	}
+	tests := []struct {
+		resp *Response
+		want []string
+	}{
+		{
+			&Response{Header: http.Header{"Server": {"nginx/1.18.0"}}},
+			[]string{"Nginx 1.18.0", "C"},
+		},
+		{
+			&Response{Body: []byte(`<meta name="generator" content="WordPress 6.4.2" />`)},
+			[]string{"WordPress 6.4.2", "PHP", "MySQL"},
+		},
+		{
+			&Response{Body: []byte(`<script src="/wp-includes/js/jquery.js"></script>`), Header: http.Header{"X-Powered-By": {"PHP/8.1.2"}}},
+			[]string{"PHP 8.1.2", "WordPress", "MySQL"},
+		},
+		{
+			&Response{Header: http.Header{"Set-Cookie": {"a=1; path=/", "PHPSESSID=abc; path=/"}}},
+			[]string{"PHP"},
+		},
+		{
+			// Vue.js 只有 js 规则，不会生成指纹，但依然会被推导出来并继续推导 JavaScript
+			&Response{Body: []byte(`<div id="__nuxt"></div>`)},
+			[]string{"Nuxt.js", "Vue.js", "JavaScript"},
+		},
+	}
+	for _, tc := range tests {
+		var got []string
+		for _, r := range wfs.Resolve(wfs.MatchIndexResponse(tc.resp)) {
+			got = append(got, r.String())
+		}
+		if diff := deep.Equal(got, tc.want); diff != nil {
+			t.Errorf("got %v; want %v; diff: %v", got, tc.want, diff)
+		}
+	}
+}
@@ -6,6 +6,7 @@ toolchain go1.22.8
 
 require (
 	github.com/PuerkitoBio/goquery v1.9.3
+	github.com/andybalholm/cascadia v1.3.2
 	github.com/deckarep/golang-set/v2 v2.7.0
 	github.com/go-test/deep v1.1.1
 	github.com/imroc/req/v3 v3.49.1
@@ -16,11 +17,12 @@ require (
 	github.com/twmb/murmur3 v1.1.8
 	github.com/urfave/cli/v3 v3.0.0-beta1
 	go.uber.org/ratelimit v0.3.1
+	golang.org/x/net v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/andybalholm/brotli v1.1.1 // indirect
-	github.com/andybalholm/cascadia v1.3.2 // indirect
 	github.com/benbjohnson/clock v1.3.0 // indirect
 	github.com/cloudflare/circl v1.5.0 // indirect
 	github.com/go-task/slim-sprig/v3 v3.0.0 // indirect
@@ -37,7 +39,6 @@ require (
 	golang.org/x/crypto v0.31.0 // indirect
 	golang.org/x/exp v0.0.0-20241215155358-4a5509556b9e // indirect
 	golang.org/x/mod v0.22.0 // indirect
-	golang.org/x/net v0.33.0 // indirect
 	golang.org/x/sync v0.10.0 // indirect
 	golang.org/x/sys v0.28.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
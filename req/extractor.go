@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/akkuman/webeye/utils"
 	"github.com/spf13/cast"
 )
 
@@ -16,7 +18,7 @@ var allowJSRedirect = true
 var (
 	cutset            = "\n\t\v\f\r"
 	reTitle           = regexp.MustCompile(`(?im)<\s*title.*>(.*?)<\s*/\s*title>`)
-	reFaviconLink     = regexp.MustCompile(`(?im)<\s*?link\s*?rel\s*?=\s*?"\s*?(shortcut icon|icon)\s*?"\s*?href\s*?=\s*?"\s*?(.+?)\s*?"\s*?>`)
+	reFaviconLink     = regexp.MustCompile(`(?im)<\s*?link\s*?rel\s*?=\s*?"\s*?(shortcut icon|icon|apple-touch-icon|apple-touch-icon-precomposed|mask-icon)\s*?"\s*?href\s*?=\s*?"\s*?(.+?)\s*?"\s*?>`)
 	reRedirectURLInJS = []*regexp.Regexp{
 		regexp.MustCompile(`(?im)\.?location\.(open|replace|assign)\(['"]?(?P<uri>.*?)['"]?\)`),
 		regexp.MustCompile(`(?im)\.?location(?:\.href)?\s*?=\s*?['"](?P<uri>.*?)['"]`),
@@ -109,11 +111,10 @@ func ExtractTitle(data []byte) (title string) {
 		// 对于一些纯文本，可能一个网页上就一个 SUCCESS，则把此类文本的第一行作为标题返回
 		lines := bytes.Split(data, []byte("\n"))
 		title = strings.TrimSpace(string(lines[0]))
-	} else if bytes.HasPrefix(data, []byte("{")) && bytes.HasSuffix(data, []byte("}")) {
+	} else if v, ok := utils.ParseJSON(data); ok {
 		// 对于一些首页返回 json 字典的情况，则将一些预置可能的键值作为标题返回
-		dataMap := make(map[string]interface{})
-		err = json.Unmarshal(data, &dataMap)
-		if err != nil {
+		dataMap, ok := v.(map[string]any)
+		if !ok {
 			return
 		}
 		for _, k := range titleGuestKeysInJSON {
@@ -126,31 +127,70 @@ func ExtractTitle(data []byte) (title string) {
 	return
 }
 
-// extractFaviconLink 从响应体中提取 favicon 链接
-func ExtractFaviconLink(data []byte) (links []string) {
-	fReMatch := func(s string) {
-		for _, match := range reFaviconLink.FindAllStringSubmatch(string(s), -1) {
-			links = append(links, match[2])
+// faviconRels 会被当作图标的 link rel
+var faviconRels = []string{"icon", "apple-touch-icon", "apple-touch-icon-precomposed", "mask-icon"}
+
+// hasRel 判断 link 的 rel 是否包含任意一个值，rel 可以包含多个以空白分隔的值，如 shortcut icon
+func hasRel(s *goquery.Selection, rels ...string) bool {
+	rel, _ := s.Attr("rel")
+	for _, v := range strings.Fields(strings.ToLower(rel)) {
+		if slices.Contains(rels, v) {
+			return true
 		}
 	}
+	return false
+}
+
+// extractLinkHref 提取 rel 包含任意一个值的 link 的 href，解析失败时返回 nil
+func extractLinkHref(data []byte, rels ...string) (links []string) {
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
 	if err != nil {
-		fReMatch(string(data))
-		return
+		return nil
 	}
-	doc.Find("link[rel=\"shortcut icon\"],link[rel=\"icon\"]").Each(func(i int, s *goquery.Selection) {
-		href, ok := s.Attr("href")
-		if !ok {
+	doc.Find("link[rel][href]").Each(func(i int, s *goquery.Selection) {
+		if !hasRel(s, rels...) {
 			return
 		}
-		href = strings.TrimSpace(href)
+		href := strings.TrimSpace(s.AttrOr("href", ""))
 		if href == "" {
 			return
 		}
 		links = append(links, href)
 	})
+	return
+}
+
+// ExtractFaviconLink 从响应体中提取 favicon 链接，包括 icon、shortcut icon、apple-touch-icon 和 mask-icon
+// 链接可能是 data URI，需要使用 DecodeDataURI 解码
+func ExtractFaviconLink(data []byte) (links []string) {
+	links = extractLinkHref(data, faviconRels...)
 	if len(links) == 0 {
-		fReMatch(string(data))
+		for _, match := range reFaviconLink.FindAllStringSubmatch(string(data), -1) {
+			links = append(links, match[2])
+		}
+	}
+	return
+}
+
+// ExtractManifestLink 从响应体中提取 web app manifest 的链接
+func ExtractManifestLink(data []byte) []string {
+	return extractLinkHref(data, "manifest")
+}
+
+// ExtractManifestIcons 从 web app manifest 中提取图标链接，链接是相对于 manifest 的
+func ExtractManifestIcons(data []byte) (links []string) {
+	var manifest struct {
+		Icons []struct {
+			Src string `json:"src"`
+		} `json:"icons"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil
+	}
+	for _, icon := range manifest.Icons {
+		if src := strings.TrimSpace(icon.Src); src != "" {
+			links = append(links, src)
+		}
 	}
 	return
 }
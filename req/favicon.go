@@ -1,14 +1,12 @@
 package req
 
 import (
-	"bytes"
-	"crypto/md5"
 	"encoding/base64"
-	"encoding/hex"
 	"fmt"
-	"hash"
+	"net/url"
+	"strings"
 
-	"github.com/twmb/murmur3"
+	"github.com/akkuman/webeye/utils"
 )
 
 // 和 shodan 相同的 iconhash 算法，来自 https://github.com/Becivells/iconhash/blob/dev/config.go
@@ -16,36 +14,62 @@ type Favicon struct {
 	URL  string `json:"url"`
 	MMH3 string `json:"mmh3"`
 	MD5  string `json:"md5"`
-	Data []byte `json:"-"`
+	// 感知哈希，用于匹配重新编码或缩放过的图标，无法解码的图标（如 svg）为空
+	PHash string `json:"phash,omitempty"`
+	Data  []byte `json:"-"`
 }
 
-// standBase64 计算 base64 的值
-func standBase64(braw []byte) []byte {
-	bckd := base64.StdEncoding.EncodeToString(braw)
-	var buffer bytes.Buffer
-	for i := 0; i < len(bckd); i++ {
-		ch := bckd[i]
-		buffer.WriteByte(ch)
-		if (i+1)%76 == 0 {
-			buffer.WriteByte('\n')
-		}
-	}
-	buffer.WriteByte('\n')
-	return buffer.Bytes()
+func ShodanIconHash(content []byte) string {
+	return utils.ShodanHash(content)
 }
 
-func mmh3Hash32(raw []byte) string {
-	var h32 hash.Hash32 = murmur3.New32()
-	h32.Write(raw)
-	return fmt.Sprintf("%d", int32(h32.Sum32()))
+func MD5IconHash(content []byte) string {
+	return utils.MD5Hex(content)
 }
 
-func ShodanIconHash(content []byte) string {
-	return mmh3Hash32(standBase64(content))
+// NewFavicon 计算图标数据的 hash
+func NewFavicon(faviconURL string, data []byte) Favicon {
+	return Favicon{
+		URL:   faviconURL,
+		MMH3:  ShodanIconHash(data),
+		MD5:   MD5IconHash(data),
+		PHash: utils.PerceptualHash(data),
+		Data:  data,
+	}
 }
 
-func MD5IconHash(content []byte) string {
-	m := md5.New()
-	m.Write(content)
-	return hex.EncodeToString(m.Sum(nil))
+// IsDataURI 判断链接是否是 data URI
+func IsDataURI(link string) bool {
+	return len(link) >= 5 && strings.EqualFold(link[:5], "data:")
+}
+
+// DecodeDataURI 解码 data URI，格式为 data:[<mediatype>][;base64],<data>，返回数据和小写的媒体类型（不含参数）
+func DecodeDataURI(uri string) (data []byte, mediaType string, err error) {
+	if !IsDataURI(uri) {
+		return nil, "", fmt.Errorf("不是 data URI")
+	}
+	meta, payload, ok := strings.Cut(uri[5:], ",")
+	if !ok {
+		return nil, "", fmt.Errorf("data URI 缺少 ,")
+	}
+	params := strings.Split(meta, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(params[0]))
+	if mediaType == "" {
+		mediaType = "text/plain"
+	}
+	if strings.EqualFold(strings.TrimSpace(params[len(params)-1]), "base64") {
+		payload, err = url.PathUnescape(payload)
+		if err != nil {
+			return nil, "", err
+		}
+		// 部分页面的 base64 中带有换行或空格
+		payload = strings.Join(strings.Fields(payload), "")
+		data, err = base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(payload, "="))
+		}
+		return data, mediaType, err
+	}
+	payload, err = url.PathUnescape(payload)
+	return []byte(payload), mediaType, err
 }
@@ -34,9 +34,12 @@ var KeyContextScope CTXKey = "scope:allow_redirect"
 
 type HttpRawData struct {
 	URL         url.URL            //当前 URL
+	Proto       string             //协议版本，如 HTTP/1.1
 	Header      http.Header        //响应头
 	StatusCode  int                //状态码
 	Body        []byte             //响应体
+	BodyMD5     string             //响应体 md5
+	BodyMMH3    string             //响应体 mmh3，和 shodan 的 http.html_hash 一致
 	Title       string             //标题
 	URLS        []string           //提取到的 URL
 	ICPS        []string           //提取到的 ICP 备案
@@ -58,6 +61,41 @@ func (hrd *HttpRawData) FaviconHashList() []string {
 	return favicons
 }
 
+// FaviconPHashList 返回所有图标的感知哈希
+func (hrd *HttpRawData) FaviconPHashList() []string {
+	var phashes []string
+	for _, v := range hrd.FaviconHash {
+		if v.PHash != "" {
+			phashes = append(phashes, v.PHash)
+		}
+	}
+	return phashes
+}
+
+// BodyHashList 返回响应体的 md5 和 mmh3，没有计算过时返回 nil，由指纹匹配时根据响应体计算
+func (hrd *HttpRawData) BodyHashList() []string {
+	if hrd.BodyMD5 == "" || hrd.BodyMMH3 == "" {
+		return nil
+	}
+	return []string{hrd.BodyMD5, hrd.BodyMMH3}
+}
+
+// FingerResponse 转换为指纹匹配使用的响应数据
+func (hrd *HttpRawData) FingerResponse() *finger.Response {
+	return &finger.Response{
+		URL:            hrd.URL.String(),
+		Proto:          hrd.Proto,
+		StatusCode:     hrd.StatusCode,
+		Header:         hrd.Header,
+		Body:           hrd.Body,
+		Title:          hrd.Title,
+		Favicons:       hrd.FaviconHashList(),
+		FaviconPHashes: hrd.FaviconPHashList(),
+		BodyHashes:     hrd.BodyHashList(),
+		Certs:          hrd.X509Cert,
+	}
+}
+
 type Options struct {
 	// 最大跟随跳转次数
 	MaxRedirects int
@@ -105,12 +143,21 @@ func NewWebX(opt *Options) *WebX {
 // Request 发送请求，wf 为请求指纹，如果传 nil，代表为首页或 favicon 指纹
 // ctx 可以设置键，详情可参见变量 KeyContextScope
 func (x *WebX) Request(ctx context.Context, targetURL string, wf *finger.WebFinger) ([]HttpRawData, error) {
+	if wf == nil {
+		return x.RequestProbe(ctx, targetURL, nil)
+	}
+	return x.RequestProbe(ctx, targetURL, &wf.Request)
+}
+
+// RequestProbe 按请求信息发送请求，请求相同的多个自定义指纹可以共用一次请求，ri 为 nil 时代表首页或 favicon 指纹
+// ctx 可以设置键，详情可参见变量 KeyContextScope
+func (x *WebX) RequestProbe(ctx context.Context, targetURL string, ri *finger.RequestInfo) ([]HttpRawData, error) {
 	var v []HttpRawData
 	var cacheKey string
 	if x.cache != nil {
 		d, _ := json.Marshal(map[string]any{
 			"url": targetURL,
-			"finger": wf,
+			"request": ri,
 		})
 		cacheKey = utils.MD5Hex(d)
 		if err := x.cache.Get(cacheKey, &v); err == nil {
@@ -119,7 +166,7 @@ func (x *WebX) Request(ctx context.Context, targetURL string, wf *finger.WebFing
 		}
 	}
 	// 没有中缓存就请求一次
-	hrds, err := x.doWebHTMLRequest(ctx, targetURL, wf)
+	hrds, err := x.doWebHTMLRequest(ctx, targetURL, ri)
 	if err != nil {
 		return hrds, err
 	}
@@ -131,12 +178,12 @@ func (x *WebX) Request(ctx context.Context, targetURL string, wf *finger.WebFing
 }
 
 // getResponse 发送请求获取响应，注意：返回的 *http.Response 将不能再被读取 body
-func (x *WebX) getResponse(ctx context.Context, rawURL string, wf *finger.WebFinger) ([]byte, *req.Response, error) {
+func (x *WebX) getResponse(ctx context.Context, rawURL string, ri *finger.RequestInfo) ([]byte, *req.Response, error) {
 	x.limiter.Take()
 	targetURL := rawURL
 	request := x.client.R().DisableAutoReadResponse().SetContext(ctx)
 	requestMethod := http.MethodGet
-	if wf != nil {
+	if ri != nil {
 		parsedURL, err := url.Parse(rawURL)
 		if err != nil {
 			return nil, nil, err
@@ -144,14 +191,14 @@ func (x *WebX) getResponse(ctx context.Context, rawURL string, wf *finger.WebFin
 		newURL := &url.URL{
 			Scheme: parsedURL.Scheme,
 			Host: parsedURL.Host,
-			Path: utils.AppendURLPath(parsedURL.Path, wf.Request.Path),
+			Path: utils.AppendURLPath(parsedURL.Path, ri.Path),
 		}
 		targetURL = newURL.String()
 		// 非首页 favicon 指纹
-		request.SetHeaders(wf.Request.RequestHeader)
-		request.SetBodyBytes(wf.Request.RequestData)
-		if wf.Request.RequestMethod != "" {
-			requestMethod = wf.Request.RequestMethod
+		request.SetHeaders(ri.RequestHeader)
+		request.SetBodyBytes(ri.RequestData)
+		if ri.RequestMethod != "" {
+			requestMethod = ri.RequestMethod
 		}
 	}
 	resp, err := request.Send(strings.ToUpper(requestMethod), targetURL)
@@ -293,11 +340,11 @@ func matchDomain(hostname, allowedDomain string) bool {
 	return false
 }
 
-// 首页加跳转请求，返回元数据列表，wf 不为 nil，代表是自定义请求
-func (x *WebX) doWebHTMLRequest(ctx context.Context, targetURL string, wf *finger.WebFinger) ([]HttpRawData, error) {
+// 首页加跳转请求，返回元数据列表，ri 不为 nil，代表是自定义请求
+func (x *WebX) doWebHTMLRequest(ctx context.Context, targetURL string, ri *finger.RequestInfo) ([]HttpRawData, error) {
 	// 首页请求
 	HttpRawDataList := make([]HttpRawData, 0)
-	body, httpresp, err := x.getResponse(ctx, targetURL, wf)
+	body, httpresp, err := x.getResponse(ctx, targetURL, ri)
 	if err != nil {
 		return HttpRawDataList, err
 	}
@@ -305,7 +352,7 @@ func (x *WebX) doWebHTMLRequest(ctx context.Context, targetURL string, wf *finge
 	if err != nil {
 		return []HttpRawData{hrd}, err
 	}
-	if wf != nil {
+	if ri != nil {
 		// 对于自定义请求的情况，不需要进行跟随跳转，也不需要请求 favicon，直接执行自定义请求即可
 		return []HttpRawData{hrd}, nil
 	}
@@ -352,10 +399,13 @@ func (x *WebX) doWebHTMLRequest(ctx context.Context, targetURL string, wf *finge
 func (x *WebX) responseToHttpRawData(resp *http.Response, respbody []byte) (HttpRawData, error) {
 	http_raw_data := HttpRawData{
 		URL:        *resp.Request.URL,
+		Proto:      resp.Proto,
 		StatusCode: resp.StatusCode,
 		Header:     resp.Header,
 		URLS:       make([]string, 0),
 		Body:       respbody,
+		BodyMD5:    utils.MD5Hex(respbody),
+		BodyMMH3:   utils.MMH3Hash(respbody),
 		Email:      make([]string, 0),
 		ICPS:       make([]string, 0),
 		Title:      "",
@@ -385,35 +435,100 @@ func (x *WebX) responseToHttpRawData(resp *http.Response, respbody []byte) (Http
 	return http_raw_data, nil
 }
 
+// getFavicon 获取页面的所有图标，来源包括 link 标签、web app manifest 和 /favicon.ico
+// data URI 直接在本地解码，相同链接只请求一次，内容相同的图标只保留一个
 func (x *WebX) getFavicon(ctx context.Context, resp *http.Response, body []byte) (favicons []Favicon) {
 	// 对于服务器错误的情况，直接跳过
 	if resp.StatusCode >= 500 && resp.StatusCode < 600 {
 		return
 	}
+	if resp.Request == nil || resp.Request.URL == nil {
+		return
+	}
 	links := ExtractFaviconLink(body)
-	// 浏览器在找不到 favicon 的情况下会自动访问该路径
-	links = append(links, "/favicon.ico")
-	for j := range links {
-		if resp.Request == nil || resp.Request.URL == nil {
-			continue
-		}
-		iconURL, err := resp.Request.URL.Parse(links[j])
+	for _, link := range ExtractManifestLink(body) {
+		manifestURL, err := resp.Request.URL.Parse(link)
 		if err != nil {
 			continue
 		}
-		faviconURL := iconURL.String()
-		if strings.HasPrefix(links[j], "http://") || strings.HasPrefix(links[j], "https://") {
-			faviconURL = links[j]
+		links = append(links, x.getManifestIcons(ctx, manifestURL)...)
+	}
+	// 浏览器在找不到 favicon 的情况下会自动访问该路径
+	links = append(links, "/favicon.ico")
+	seenURLs := mapset.NewThreadUnsafeSet[string]()
+	seenHashes := mapset.NewThreadUnsafeSet[string]()
+	for _, link := range links {
+		var favicon Favicon
+		if IsDataURI(link) {
+			data, mediaType, err := DecodeDataURI(link)
+			if err != nil || !strings.HasPrefix(mediaType, "image/") || len(data) == 0 {
+				continue
+			}
+			favicon = NewFavicon(link, data)
+		} else {
+			iconURL, err := resp.Request.URL.Parse(link)
+			if err != nil {
+				continue
+			}
+			faviconURL := iconURL.String()
+			if strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") {
+				faviconURL = link
+			}
+			if !seenURLs.Add(faviconURL) {
+				continue
+			}
+			fr := x.GetFav(ctx, faviconURL)
+			if fr.Error != nil {
+				continue
+			}
+			favicon = fr.Favicon
 		}
-		fr := x.GetFav(ctx, faviconURL)
-		if fr.Error == nil {
-			favicons = append(favicons, fr.Favicon)
+		if seenHashes.Add(favicon.MD5) {
+			favicons = append(favicons, favicon)
 		}
 	}
 	return
 }
 
+func buildManifestCacheKey(manifestURL string) string {
+	return fmt.Sprintf("manifest:%s", manifestURL)
+}
 
+// getManifestIcons 获取 web app manifest 中的图标链接，相对链接会转换为绝对链接
+func (x *WebX) getManifestIcons(ctx context.Context, manifestURL *url.URL) (links []string) {
+	cacheKey := buildManifestCacheKey(manifestURL.String())
+	if x.cache != nil {
+		if err := x.cache.Get(cacheKey, &links); err == nil {
+			return links
+		}
+	}
+	x.limiter.Take()
+	resp, err := x.client.R().SetContext(ctx).DisableAutoReadResponse().Get(manifestURL.String())
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil
+	}
+	for _, src := range ExtractManifestIcons(data) {
+		if IsDataURI(src) {
+			links = append(links, src)
+			continue
+		}
+		if iconURL, err := manifestURL.Parse(src); err == nil {
+			links = append(links, iconURL.String())
+		}
+	}
+	if x.cache != nil {
+		x.cache.Set(cacheKey, links, 24*time.Hour)
+	}
+	return links
+}
 
 // 获取标题，URL 列表，ICP 备案
 func GetWebTitleAndUrlsAndIPC(body []byte) (string, []string, []string) {
@@ -481,12 +596,7 @@ func (x *WebX) GetFav(ctx context.Context, faviconURL string) FavCacheStruct {
 	if !strings.Contains(strings.ToLower(resp.GetContentType()), "image") || strings.Contains(string(respbody), "<html>") {
 		return FavCacheStruct{Error: fmt.Errorf("ContentType Not Image")}
 	}
-	fr := FavCacheStruct{Error: nil, Favicon: Favicon{
-		URL:  faviconURL,
-		MMH3: ShodanIconHash(respbody),
-		MD5:  MD5IconHash(respbody),
-		Data: respbody,
-	}}
+	fr := FavCacheStruct{Error: nil, Favicon: NewFavicon(faviconURL, respbody)}
 	if x.cache != nil {
 		x.cache.Set(buildFavCacheKey(faviconURL), fr.Favicon, 24*time.Hour)
 	}
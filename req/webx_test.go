@@ -2,10 +2,15 @@ package req
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
+
+	"github.com/akkuman/webeye/finger"
+	"github.com/go-test/deep"
 )
 
 func TestWebxGetRedirectURL(t *testing.T) {
@@ -181,4 +186,119 @@ func TestStatusCodeTitle(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestExtractFaviconLink(t *testing.T) {
+	tests := []struct {
+		input         string
+		wantFavicons  []string
+		wantManifests []string
+	}{
+		{
+			input:        `<link rel="shortcut icon" href="/favicon.ico"><link rel="stylesheet" href="/a.css">`,
+			wantFavicons: []string{"/favicon.ico"},
+		},
+		{
+			input:         `<link rel="Apple-Touch-Icon" href="/apple.png"><link rel="mask-icon" href="/mask.svg" color="#000"><link rel="manifest" href="/manifest.json">`,
+			wantFavicons:  []string{"/apple.png", "/mask.svg"},
+			wantManifests: []string{"/manifest.json"},
+		},
+		{
+			input:        `<link rel="icon" href="data:image/png;base64,iVBORw0KGgo=">`,
+			wantFavicons: []string{"data:image/png;base64,iVBORw0KGgo="},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("ExtractFaviconLink-%s", tc.input), func(t *testing.T) {
+			if diff := deep.Equal(ExtractFaviconLink([]byte(tc.input)), tc.wantFavicons); diff != nil {
+				t.Errorf("favicons: %v", diff)
+			}
+			if diff := deep.Equal(ExtractManifestLink([]byte(tc.input)), tc.wantManifests); diff != nil {
+				t.Errorf("manifests: %v", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeDataURI(t *testing.T) {
+	tests := []struct {
+		input         string
+		wantData      string
+		wantMediaType string
+		wantErr       bool
+	}{
+		{"data:image/png;base64,aGVsbG8=", "hello", "image/png", false},
+		{"DATA:image/svg+xml;charset=utf-8,%3Csvg%3E%3C/svg%3E", "<svg></svg>", "image/svg+xml", false},
+		{"data:,hello", "hello", "text/plain", false},
+		{"data:image/png;base64,aGVs\nbG8", "hello", "image/png", false},
+		{"data:image/png;base64", "", "", true},
+		{"/favicon.ico", "", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("DecodeDataURI-%s", tc.input), func(t *testing.T) {
+			data, mediaType, err := DecodeDataURI(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v; want error %v", err, tc.wantErr)
+			}
+			if string(data) != tc.wantData || mediaType != tc.wantMediaType {
+				t.Errorf("got %#v; want %#v", []any{string(data), mediaType}, []any{tc.wantData, tc.wantMediaType})
+			}
+		})
+	}
+}
+
+func TestGetFavicon(t *testing.T) {
+	icon := []byte("\x89PNG\r\n\x1a\nicon")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/x-icon")
+		w.Write(icon)
+	})
+	mux.HandleFunc("/static/apple.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("apple"))
+	})
+	mux.HandleFunc("/static/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"icons": [{"src": "apple.png"}, {"src": "/favicon.ico"}, {"src": "data:image/png;base64,aWNvbg=="}]}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	body := []byte(`<link rel="apple-touch-icon" href="/static/apple.png"><link rel="manifest" href="/static/manifest.json">` +
+		`<link rel="icon" href="data:image/png;base64,` + base64.StdEncoding.EncodeToString(icon) + `">`)
+	u, _ := url.Parse(server.URL + "/")
+	resp := &http.Response{StatusCode: 200, Request: &http.Request{URL: u}}
+	webxIns := NewWebX(&Options{MaxRedirects: 3, RateLimit: 1000, Client: NewDefaultHTTPClient()})
+	var got []string
+	for _, favicon := range webxIns.getFavicon(context.Background(), resp, body) {
+		got = append(got, string(favicon.Data))
+	}
+	// data URI 中的图标和 /favicon.ico 内容相同，只保留一个
+	want := []string{"apple", string(icon), "icon"}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestRequestWebFinger(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Method+" "+r.URL.Path)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	webxIns := NewWebX(&Options{MaxRedirects: 3, RateLimit: 1000, Client: NewDefaultHTTPClient()})
+	wf := &finger.WebFinger{Request: finger.RequestInfo{Path: "/api/version", RequestMethod: "post"}}
+	for _, fn := range []func() ([]HttpRawData, error){
+		func() ([]HttpRawData, error) { return webxIns.Request(context.Background(), server.URL+"/", wf) },
+		func() ([]HttpRawData, error) { return webxIns.RequestProbe(context.Background(), server.URL+"/", &wf.Request) },
+	} {
+		if _, err := fn(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"POST /api/version", "POST /api/version"}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"hash"
+
+	"github.com/twmb/murmur3"
+)
+
+// standBase64 计算 base64 的值，每 76 个字符换行，和 python 的 base64.encodebytes 一致
+func standBase64(braw []byte) []byte {
+	bckd := base64.StdEncoding.EncodeToString(braw)
+	var buffer bytes.Buffer
+	for i := 0; i < len(bckd); i++ {
+		ch := bckd[i]
+		buffer.WriteByte(ch)
+		if (i+1)%76 == 0 {
+			buffer.WriteByte('\n')
+		}
+	}
+	buffer.WriteByte('\n')
+	return buffer.Bytes()
+}
+
+func mmh3Hash32(raw []byte) string {
+	var h32 hash.Hash32 = murmur3.New32()
+	h32.Write(raw)
+	return fmt.Sprintf("%d", int32(h32.Sum32()))
+}
+
+// ShodanHash 和 shodan 相同的 mmh3 hash 算法，来自 https://github.com/Becivells/iconhash/blob/dev/config.go
+func ShodanHash(content []byte) string {
+	return mmh3Hash32(standBase64(content))
+}
+
+// MMH3Hash 计算数据的 mmh3 hash，和 shodan 的 http.html_hash 一致，不做 base64 编码
+func MMH3Hash(content []byte) string {
+	return mmh3Hash32(content)
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// IsICO 判断数据是否是 ico 或 cur 格式
+func IsICO(data []byte) bool {
+	return len(data) >= 6 && data[0] == 0 && data[1] == 0 && (data[2] == 1 || data[2] == 2) && data[3] == 0
+}
+
+// DecodeICO 解码 ico 文件中尺寸最大的图片，支持内嵌 png 以及 1、4、8、24、32 位的 bmp
+func DecodeICO(data []byte) (image.Image, error) {
+	if !IsICO(data) {
+		return nil, fmt.Errorf("不是 ico 文件")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 || len(data) < 6+16*count {
+		return nil, fmt.Errorf("ico 文件目录不完整")
+	}
+	var best []byte
+	bestSize, bestBits := -1, -1
+	for i := 0; i < count; i++ {
+		entry := data[6+16*i : 6+16*(i+1)]
+		width, height := int(entry[0]), int(entry[1])
+		// 0 代表 256
+		if width == 0 {
+			width = 256
+		}
+		if height == 0 {
+			height = 256
+		}
+		bits := int(binary.LittleEndian.Uint16(entry[6:8]))
+		size := int(binary.LittleEndian.Uint32(entry[8:12]))
+		offset := int(binary.LittleEndian.Uint32(entry[12:16]))
+		if size <= 0 || offset < 0 || offset+size > len(data) || offset+size < offset {
+			continue
+		}
+		if width*height > bestSize || width*height == bestSize && bits > bestBits {
+			best, bestSize, bestBits = data[offset:offset+size], width*height, bits
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("ico 文件中没有有效的图片")
+	}
+	if bytes.HasPrefix(best, pngSignature) {
+		return DecodeImage(best)
+	}
+	return decodeICOBitmap(best)
+}
+
+// decodeICOBitmap 解码 ico 中不带文件头的 bmp，高度是图片和透明遮罩的高度之和
+func decodeICOBitmap(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, fmt.Errorf("ico 中的 bmp 不完整")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12]))) / 2
+	bits := int(binary.LittleEndian.Uint16(data[14:16]))
+	compression := binary.LittleEndian.Uint32(data[16:20])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:36]))
+	// 32 位图片可能使用 BI_BITFIELDS，颜色掩码一般就是 BGRA 的顺序
+	if compression != 0 && !(compression == 3 && bits == 32) {
+		return nil, fmt.Errorf("不支持压缩的 bmp")
+	}
+	if width <= 0 || height <= 0 || width > 1024 || height > 1024 || headerSize < 40 || headerSize > len(data) {
+		return nil, fmt.Errorf("bmp 尺寸不合法")
+	}
+	pos := headerSize
+	if compression == 3 && headerSize == 40 {
+		pos += 12
+	}
+	var palette []color.NRGBA
+	switch bits {
+	case 1, 4, 8:
+		if colorsUsed == 0 || colorsUsed > 1<<bits {
+			colorsUsed = 1 << bits
+		}
+		if pos+4*colorsUsed > len(data) {
+			return nil, fmt.Errorf("bmp 调色板不完整")
+		}
+		for i := 0; i < colorsUsed; i++ {
+			p := data[pos+4*i:]
+			palette = append(palette, color.NRGBA{p[2], p[1], p[0], 0xff})
+		}
+		pos += 4 * colorsUsed
+	case 24, 32:
+	default:
+		return nil, fmt.Errorf("不支持 %d 位的 bmp", bits)
+	}
+	stride := (width*bits + 31) / 32 * 4
+	maskStride := (width + 31) / 32 * 4
+	if pos+stride*height > len(data) {
+		return nil, fmt.Errorf("bmp 数据不完整")
+	}
+	mask := data[pos+stride*height:]
+	hasMask := len(mask) >= maskStride*height
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		// 行是从下往上存储的
+		row := data[pos+stride*(height-1-y):]
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch bits {
+			case 32:
+				c = color.NRGBA{row[4*x+2], row[4*x+1], row[4*x], row[4*x+3]}
+				hasAlpha = hasAlpha || c.A != 0
+			case 24:
+				c = color.NRGBA{row[3*x+2], row[3*x+1], row[3*x], 0xff}
+			default:
+				perByte := 8 / bits
+				index := int(row[x/perByte]>>(8-bits*(x%perByte+1))) & (1<<bits - 1)
+				if index < len(palette) {
+					c = palette[index]
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	// 没有 alpha 通道时使用透明遮罩，遮罩为 1 的像素是透明的
+	if !hasAlpha {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				c := img.NRGBAAt(x, y)
+				c.A = 0xff
+				if hasMask && mask[maskStride*(height-1-y)+x/8]&(0x80>>(x%8)) != 0 {
+					c.A = 0
+				}
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// SniffJSON 判断数据是否像 json 对象或数组，即去掉首尾空白后被 {} 或 [] 包裹
+func SniffJSON(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) >= 2 &&
+		(data[0] == '{' && data[len(data)-1] == '}' || data[0] == '[' && data[len(data)-1] == ']')
+}
+
+// ParseJSON 解析 json 对象或数组，数字解析为 json.Number 以保留原始文本
+// 数据不是 json 对象或数组，或者解析失败时返回 false
+func ParseJSON(data []byte) (any, bool) {
+	if !SniffJSON(data) {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, false
+	}
+	// 忽略 {}{} 这类后面还有其他数据的情况
+	if decoder.More() {
+		return nil, false
+	}
+	return v, true
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want any
+		ok   bool
+	}{
+		{` {"version": {"number": 7.10}} `, map[string]any{"version": map[string]any{"number": json.Number("7.10")}}, true},
+		{`[1, "a"]`, []any{json.Number("1"), "a"}, true},
+		{`"string"`, nil, false},
+		{`{"a": 1}{"b": 2}`, nil, false},
+		{`{not json}`, nil, false},
+		{`<html></html>`, nil, false},
+	}
+	for _, tc := range tests {
+		got, ok := ParseJSON([]byte(tc.data))
+		if ok != tc.ok {
+			t.Errorf("ParseJSON(%s) ok = %v; want %v", tc.data, ok, tc.ok)
+		}
+		if diff := deep.Equal(got, tc.want); diff != nil {
+			t.Errorf("ParseJSON(%s) = %v; want %v", tc.data, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"strconv"
+)
+
+// maxImageSide 解码图片允许的最大边长，避免很小的文件解压出巨大的图片
+const maxImageSide = 4096
+
+// DecodeImage 解码 ico、png、gif、jpeg 图片
+func DecodeImage(data []byte) (image.Image, error) {
+	if IsICO(data) {
+		return DecodeICO(data)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width > maxImageSide || config.Height > maxImageSide {
+		return nil, fmt.Errorf("图片尺寸 %dx%d 过大", config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// DHash 计算图片的差异哈希（dHash），结果为 64 位
+// 图片先叠加到白色背景上，转为灰度并缩小为 9x8，每一位表示同一行中左边的像素是否比右边暗
+// 重新编码、缩放或轻微修改过的图片哈希值之间的汉明距离很小
+func DHash(img image.Image) uint64 {
+	const w, h = 9, 8
+	bounds := img.Bounds()
+	var gray [h][w]float64
+	for y := 0; y < h; y++ {
+		y0, y1 := cellRange(bounds.Min.Y, bounds.Dy(), y, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := cellRange(bounds.Min.X, bounds.Dx(), x, w)
+			var sum float64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					// RGBA 返回的是预乘 alpha 的值，加上 0xffff-a 相当于叠加到白色背景上
+					r, g, b, a := img.At(px, py).RGBA()
+					bg := float64(0xffff - a)
+					sum += 0.299*(float64(r)+bg) + 0.587*(float64(g)+bg) + 0.114*(float64(b)+bg)
+				}
+			}
+			gray[y][x] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if gray[y][x] < gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// cellRange 返回把长度为 size 的边平均分成 n 份后第 i 份的像素范围，至少包含一个像素
+func cellRange(start, size, i, n int) (int, int) {
+	lo := start + i*size/n
+	hi := start + (i+1)*size/n
+	if hi <= lo {
+		hi = lo + 1
+	}
+	return lo, hi
+}
+
+// PerceptualHash 计算图标的感知哈希，结果为 16 位十六进制的 dHash，无法解码的图片（如 svg）返回空字符串
+func PerceptualHash(data []byte) string {
+	img, err := DecodeImage(data)
+	if err != nil || img.Bounds().Empty() {
+		return ""
+	}
+	return FormatPHash(DHash(img))
+}
+
+// FormatPHash 将感知哈希格式化为 16 位十六进制
+func FormatPHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// ParsePHash 解析 16 位十六进制的感知哈希
+func ParsePHash(s string) (uint64, error) {
+	if len(s) != 16 {
+		return 0, fmt.Errorf("感知哈希 %s 需要是 16 位十六进制", s)
+	}
+	hash, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("感知哈希 %s 需要是 16 位十六进制", s)
+	}
+	return hash, nil
+}
+
+// HammingDistance 计算两个哈希之间不同的位数
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
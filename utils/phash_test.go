@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testIcon 生成一个左上角为深色圆、其余为渐变的图标
+func testIcon(size int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := x-size/3, y-size/3
+			c := color.NRGBA{uint8(255 * x / size), uint8(255 * y / size), 128, 255}
+			if dx*dx+dy*dy < size*size/16 {
+				c = color.NRGBA{20, 20, 60, 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeICO 生成只包含一张图片的 ico，bmp 为 true 时使用 32 位 bmp，否则内嵌 png
+func encodeICO(t *testing.T, img *image.NRGBA, bmp bool) []byte {
+	size := img.Bounds().Dx()
+	payload := encodePNG(t, img)
+	if bmp {
+		var buf bytes.Buffer
+		header := make([]byte, 40)
+		binary.LittleEndian.PutUint32(header[0:], 40)
+		binary.LittleEndian.PutUint32(header[4:], uint32(size))
+		binary.LittleEndian.PutUint32(header[8:], uint32(size*2))
+		binary.LittleEndian.PutUint16(header[12:], 1)
+		binary.LittleEndian.PutUint16(header[14:], 32)
+		buf.Write(header)
+		for y := size - 1; y >= 0; y-- {
+			for x := 0; x < size; x++ {
+				c := img.NRGBAAt(x, y)
+				buf.Write([]byte{c.B, c.G, c.R, c.A})
+			}
+		}
+		buf.Write(make([]byte, (size+31)/32*4*size))
+		payload = buf.Bytes()
+	}
+	ico := []byte{0, 0, 1, 0, 1, 0}
+	entry := make([]byte, 16)
+	entry[0], entry[1] = byte(size), byte(size)
+	binary.LittleEndian.PutUint16(entry[4:], 1)
+	binary.LittleEndian.PutUint16(entry[6:], 32)
+	binary.LittleEndian.PutUint32(entry[8:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(entry[12:], 22)
+	ico = append(ico, entry...)
+	return append(ico, payload...)
+}
+
+func TestPerceptualHash(t *testing.T) {
+	base := testIcon(64)
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, base, &jpeg.Options{Quality: 60}); err != nil {
+		t.Fatal(err)
+	}
+	want := PerceptualHash(encodePNG(t, base))
+	if want == "" {
+		t.Fatal("PerceptualHash(png) is empty")
+	}
+	wantHash, err := ParsePHash(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		data        []byte
+		maxDistance int
+	}{
+		{"ico-png", encodeICO(t, base, false), 0},
+		{"ico-bmp", encodeICO(t, base, true), 0},
+		{"resized", encodePNG(t, testIcon(32)), 6},
+		{"jpeg", jpg.Bytes(), 6},
+	}
+	for _, tc := range tests {
+		got := PerceptualHash(tc.data)
+		hash, err := ParsePHash(got)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if d := HammingDistance(hash, wantHash); d > tc.maxDistance {
+			t.Errorf("%s: distance %d > %d (%s, %s)", tc.name, d, tc.maxDistance, got, want)
+		}
+	}
+	flipped := image.NewNRGBA(base.Bounds())
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			flipped.SetNRGBA(63-x, y, base.NRGBAAt(x, y))
+		}
+	}
+	hash, _ := ParsePHash(PerceptualHash(encodePNG(t, flipped)))
+	if d := HammingDistance(hash, wantHash); d < 20 {
+		t.Errorf("flipped: distance %d < 20", d)
+	}
+	for _, data := range [][]byte{[]byte(`<svg></svg>`), {0, 0, 1, 0, 0, 0}} {
+		if got := PerceptualHash(data); got != "" {
+			t.Errorf("PerceptualHash(%q) = %s; want empty", data, got)
+		}
+	}
+	for _, s := range []string{"abc", "zzzzzzzzzzzzzzzz"} {
+		if _, err := ParsePHash(s); err == nil {
+			t.Errorf("ParsePHash(%s): want error", s)
+		}
+	}
+}
@@ -2,42 +2,67 @@ package webeye
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/akkuman/webeye/finger"
 	"github.com/akkuman/webeye/req"
 	"github.com/akkuman/webeye/utils"
-	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/remeh/sizedwaitgroup"
 )
 
+// Options 指纹识别选项，用于 *WithOptions 系列函数，传 nil 时使用默认值
+type Options struct {
+	// 每个目标同时发送的自定义请求数，小于等于 1 时串行请求
+	// 并发请求依然受 req.WebX 的限速器限制
+	CustomConcurrency int
+	// 自定义请求出错时继续执行剩余的请求，所有错误合并后返回
+	// 默认遇到第一个错误就停止剩余的自定义请求
+	ContinueOnError bool
+	// 最低置信度，0~1，低于该值的识别结果不返回，为 0 时不过滤
+	MinConfidence float64
+}
+
 func GetWebFinger(ctx context.Context, rawURL string, wfs finger.WebFingerSystem) (res []finger.WebFingerResult, err error) {
+	return GetWebFingerWithOptions(ctx, rawURL, wfs, nil)
+}
+
+// GetWebFingerWithOptions 使用指定选项的 GetWebFinger，opt 为 nil 时使用默认值
+func GetWebFingerWithOptions(ctx context.Context, rawURL string, wfs finger.WebFingerSystem, opt *Options) (res []finger.WebFingerResult, err error) {
 	httpClient := req.NewDefaultHTTPClient()
 	webxIns := req.NewWebX(&req.Options{MaxRedirects: 3, RateLimit: 1000, Client: httpClient})
-	return DoFingerAutoScheme(ctx, webxIns, rawURL, wfs)
+	return DoFingerAutoSchemeWithOptions(ctx, webxIns, rawURL, wfs, opt)
 }
 
 // DoFingerAutoScheme 自动补充协议的指纹识别
 // 支持带 http(s):// 或不带的 rawURL
 func DoFingerAutoScheme(ctx context.Context, webxIns *req.WebX, rawURL string, wfs finger.WebFingerSystem) (res []finger.WebFingerResult, err error) {
+	return DoFingerAutoSchemeWithOptions(ctx, webxIns, rawURL, wfs, nil)
+}
+
+// DoFingerAutoSchemeWithOptions 使用指定选项的 DoFingerAutoScheme，opt 为 nil 时使用默认值
+func DoFingerAutoSchemeWithOptions(ctx context.Context, webxIns *req.WebX, rawURL string, wfs finger.WebFingerSystem, opt *Options) (res []finger.WebFingerResult, err error) {
 	u, err := utils.ParseURL(rawURL)
 	if err != nil {
 		return nil, err
 	}
 	if u.Scheme == "http" || u.Scheme == "https" {
-		res, err = DoFinger(ctx, webxIns, rawURL, wfs)
+		res, err = DoFingerWithOptions(ctx, webxIns, rawURL, wfs, opt)
 		return
 	} else if u.Scheme == "tcp" || u.Scheme == "" {
-		fingers := mapset.NewSet[finger.WebFingerResult]()
+		var fingers []finger.WebFingerResult
 		for _, scheme := range []string{"https", "http"} {
 			u.Scheme = scheme
-			fingers_, err := DoFinger(ctx, webxIns, u.String(), wfs)
-			fingers.Append(fingers_...)
+			fingers_, err := DoFingerWithOptions(ctx, webxIns, u.String(), wfs, opt)
+			fingers = append(fingers, fingers_...)
 			if err != nil {
-				return fingers.ToSlice(), err
+				return wfs.Resolve(fingers), err
 			}
 		}
-		return fingers.ToSlice(), nil
+		return wfs.Resolve(fingers), nil
 	}
 	return nil, fmt.Errorf("不支持的 url: %s", rawURL)
 }
@@ -45,31 +70,89 @@ func DoFingerAutoScheme(ctx context.Context, webxIns *req.WebX, rawURL string, w
 // DoFinger 执行指纹识别
 // targetURL 必须以 http 或 https 开头
 func DoFinger(ctx context.Context, webxIns *req.WebX, targetURL string, wfs finger.WebFingerSystem) (res []finger.WebFingerResult, err error) {
+	return DoFingerWithOptions(ctx, webxIns, targetURL, wfs, nil)
+}
+
+// DoFingerWithOptions 使用指定选项的 DoFinger，opt 为 nil 时使用默认值
+func DoFingerWithOptions(ctx context.Context, webxIns *req.WebX, targetURL string, wfs finger.WebFingerSystem, opt *Options) (res []finger.WebFingerResult, err error) {
 	if !strings.HasPrefix(targetURL, "https://") && !strings.HasPrefix(targetURL, "http://") {
 		return nil, fmt.Errorf("incorrect target url: %s", targetURL)
 	}
-	fingers := mapset.NewSet[finger.WebFingerResult]()
+	if opt == nil {
+		opt = &Options{}
+	}
+	var fingers []finger.WebFingerResult
 	// 请求首页和 favicon
 	httpRawDataList, err := webxIns.Request(ctx, targetURL, nil)
 	if err != nil {
-		return fingers.ToSlice(), err
+		return fingers, err
 	}
 	for _, hrd := range httpRawDataList {
-		fingerResult := wfs.MatchIndex(hrd.Body, hrd.Header, hrd.StatusCode, hrd.FaviconHashList())
-		fingers.Append(fingerResult...)
+		fingerResult := wfs.MatchIndexResponse(hrd.FingerResponse())
+		fingers = append(fingers, fingerResult...)
 	}
-	// 自定义请求
-	// 内部实现：自定义请求将不会跟随任何跳转
-	for _, wf := range wfs.CustomReqs {
-		hrds, err := webxIns.Request(ctx, targetURL, &wf)
-		for _, hrd := range hrds {
-			if wf.MatchKeyWord(hrd.Body, finger.HTTPHeadersToMap(hrd.Header), hrd.StatusCode) {
-				fingers.Add(finger.NewWebFingerResult(wf))
-			}
-		}
-		if err != nil {
-			return fingers.ToSlice(), err
+	// 自定义请求，请求签名相同的指纹共享同一次请求
+	customFingers, err := doCustomRequests(ctx, webxIns, targetURL, &wfs, opt)
+	fingers = append(fingers, customFingers...)
+	return finger.FilterConfidence(wfs.Resolve(fingers), opt.MinConfidence), err
+}
+
+// doCustomRequests 并发执行自定义请求并匹配指纹，结果按自定义请求的顺序返回
+// 内部实现：自定义请求将不会跟随任何跳转
+func doCustomRequests(ctx context.Context, webxIns *req.WebX, targetURL string, wfs *finger.WebFingerSystem, opt *Options) ([]finger.WebFingerResult, error) {
+	crs := wfs.CustomRequests()
+	if len(crs) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([][]finger.WebFingerResult, len(crs))
+	errs := make([]error, len(crs))
+	var firstErr error
+	var mu sync.Mutex
+	swg := sizedwaitgroup.New(max(opt.CustomConcurrency, 1))
+	for i := range crs {
+		if ctx.Err() != nil {
+			break
 		}
+		swg.Add()
+		go func() {
+			defer swg.Done()
+			if ctx.Err() != nil {
+				// 已经有请求出错，不再发送剩余的请求
+				return
+			}
+			cr := &crs[i]
+			hrds, err := webxIns.RequestProbe(ctx, targetURL, &cr.Request)
+			for _, hrd := range hrds {
+				results[i] = append(results[i], wfs.MatchCustom(cr, hrd.FingerResponse())...)
+			}
+			if err == nil {
+				return
+			}
+			if opt.ContinueOnError {
+				method := strings.ToUpper(cr.Request.RequestMethod)
+				if method == "" {
+					method = http.MethodGet
+				}
+				errs[i] = fmt.Errorf("自定义请求 %s %s 失败: %w", method, cr.Request.Path, err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+		}()
+	}
+	swg.Wait()
+	var fingers []finger.WebFingerResult
+	for _, r := range results {
+		fingers = append(fingers, r...)
+	}
+	if firstErr != nil {
+		return fingers, firstErr
 	}
-	return fingers.ToSlice(), nil
+	return fingers, errors.Join(errs...)
 }
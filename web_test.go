@@ -3,6 +3,9 @@ package webeye
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"github.com/akkuman/webeye/finger"
@@ -33,7 +36,7 @@ func TestDoFinger(t *testing.T) {
 				"name": "sangfor-ba"
 			}]`,
 			want: []finger.WebFingerResult{
-				{Name: "sangfor-ba", RootPath: "/"},
+				{Name: "sangfor-ba", RootPath: "/", Priority: 3},
 			},
 			err: nil,
 		},
@@ -52,6 +55,14 @@ func TestDoFinger(t *testing.T) {
 				t.Errorf("error = %v; want %v", err, tc.err)
 				return
 			}
+			// 命中证据和置信度与目标页面相关，这里只比较指纹本身
+			for i := range got {
+				if got[i].Confidence <= 0 {
+					t.Errorf("%s confidence = %v; want > 0", got[i].Name, got[i].Confidence)
+				}
+				got[i].Evidences = nil
+				got[i].Confidence = 0
+			}
 			if diff := deep.Equal(got, tc.want); diff != nil {
 				t.Errorf("got %#v; want %#v; diff: %#v", got, tc.want, diff)
 			}
@@ -83,7 +94,7 @@ func TestGetWebFinger(t *testing.T) {
 				"name": "sangfor-ba"
 			}]`,
 			want: []finger.WebFingerResult{
-				{Name: "sangfor-ba", RootPath: "/"},
+				{Name: "sangfor-ba", RootPath: "/", Priority: 3},
 			},
 			err: nil,
 		},
@@ -106,7 +117,7 @@ func TestGetWebFinger(t *testing.T) {
 				"name": "finereport"
 			}]`,
 			want: []finger.WebFingerResult{
-				{Name: "finereport", RootPath: "/"},
+				{Name: "finereport", RootPath: "/", Priority: 3},
 			},
 			err: nil,
 		},
@@ -124,6 +135,13 @@ func TestGetWebFinger(t *testing.T) {
 					t.Errorf("error = %v; want %v", err, tc.err)
 					return
 				}
+				for i := range got {
+					if got[i].Confidence <= 0 {
+						t.Errorf("%s confidence = %v; want > 0", got[i].Name, got[i].Confidence)
+					}
+					got[i].Evidences = nil
+					got[i].Confidence = 0
+				}
 				if diff := deep.Equal(got, tc.want); diff != nil {
 					t.Errorf("got %#v; want %#v; diff: %#v", got, tc.want, diff)
 				}
@@ -131,3 +149,58 @@ func TestGetWebFinger(t *testing.T) {
 		}
 	}
 }
+
+func TestDoFingerCustomRequests(t *testing.T) {
+	var nacosCount atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/nacos/":
+			nacosCount.Add(1)
+			w.Write([]byte("<title>Nacos</title>"))
+		case "/broken":
+			// 直接断开连接，模拟请求失败
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	wfs, err := finger.ParseWebFinger(`[
+		{"path": "/broken", "request_method": "get", "keyword": ["broken"], "name": "broken"},
+		{"path": "/nacos/", "request_method": "get", "keyword": ["nacos"], "name": "nacos"},
+		{"path": "/nacos/", "request_method": "get", "status_code": 200, "name": "nacos-console"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		opt       *Options
+		want      []string
+		wantCount int32
+		err       error
+	}{
+		{nil, nil, 0, fmt.Errorf("EOF")},
+		{&Options{CustomConcurrency: 4, ContinueOnError: true}, []string{"nacos", "nacos-console"}, 1, fmt.Errorf("自定义请求 GET /broken 失败")},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("DoFinger(%+v)", tc.opt), func(t *testing.T) {
+			nacosCount.Store(0)
+			webxIns := req.NewWebX(&req.Options{MaxRedirects: 3, Client: req.NewDefaultHTTPClient()})
+			got, err := DoFingerWithOptions(context.Background(), webxIns, ts.URL, *wfs, tc.opt)
+			if !utils.ContainsErr(err, tc.err) {
+				t.Errorf("error = %v; want %v", err, tc.err)
+			}
+			var names []string
+			for _, r := range got {
+				names = append(names, r.Name)
+			}
+			if diff := deep.Equal(names, tc.want); diff != nil {
+				t.Errorf("got %v; want %v; diff: %v", names, tc.want, diff)
+			}
+			if n := nacosCount.Load(); n != tc.wantCount {
+				t.Errorf("/nacos/ requested %d times; want %d", n, tc.wantCount)
+			}
+		})
+	}
+}